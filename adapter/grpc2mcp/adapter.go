@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func main() {
+	configFile := flag.String("config", "adapter.yaml", "tool 与 grpc 方法映射的配置文件（json 或 yaml）")
+	flag.Parse()
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer a.close()
+
+	s := server.NewMCPServer(cfg.Server.Name, cfg.Server.Version)
+	a.register(s)
+
+	port := cfg.Server.Addr
+	baseUrl := "http://localhost" + port + "/"
+	log.Printf("baseUrl is : %s", baseUrl)
+	// streamable http 与 sse 服务共用端口
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", server.NewStreamableHTTPServer(s))
+	mux.Handle("/", server.NewSSEServer(s, server.WithBaseURL(baseUrl)))
+	log.Printf("SSE server listening on : %s, streamable http endpoint is %smcp", port, baseUrl)
+	if err := http.ListenAndServe(port, mux); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+}
+
+// adapter 持有到每个上游的 grpc 连接和 tool 路由
+type adapter struct {
+	conns  map[string]*grpc.ClientConn
+	routes []*route
+}
+
+// newAdapter 加载 descriptor set、校验配置并构造路由。grpc 连接在第一次调用时才建立
+func newAdapter(cfg *Config) (*adapter, error) {
+	if len(cfg.DescriptorSets) == 0 {
+		return nil, fmt.Errorf("descriptorSets is required")
+	}
+	desc, err := loadDescriptors(cfg.DescriptorSets)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &adapter{conns: map[string]*grpc.ClientConn{}}
+	for name, uc := range cfg.Upstreams {
+		if uc.Target == "" {
+			a.close()
+			return nil, fmt.Errorf("upstream %s: target is required", name)
+		}
+		creds := insecure.NewCredentials()
+		if uc.TLS {
+			creds = credentials.NewTLS(&tls.Config{})
+		}
+		conn, err := grpc.NewClient(uc.Target, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			a.close()
+			return nil, fmt.Errorf("upstream %s: %v", name, err)
+		}
+		a.conns[name] = conn
+	}
+
+	names := map[string]bool{}
+	for i, t := range cfg.Tools {
+		routes, err := a.newRoutes(t, cfg, desc)
+		if err != nil {
+			a.close()
+			return nil, fmt.Errorf("tools[%d]: %v", i, err)
+		}
+		for _, r := range routes {
+			if names[r.name] {
+				a.close()
+				return nil, fmt.Errorf("tool %s: duplicate name", r.name)
+			}
+			names[r.name] = true
+		}
+		a.routes = append(a.routes, routes...)
+	}
+	return a, nil
+}
+
+// newRoutes 为配置中的方法构造路由，method 写成 Service/* 时每个 unary 方法一个
+func (a *adapter) newRoutes(t ToolConfig, cfg *Config, desc *descriptors) ([]*route, error) {
+	conn, ok := a.conns[t.Upstream]
+	if !ok {
+		return nil, fmt.Errorf("unknown upstream %q", t.Upstream)
+	}
+	methods, err := desc.methods(t.Method)
+	if err != nil {
+		return nil, err
+	}
+	if len(methods) > 1 && (t.Name != "" || t.Description != "") {
+		return nil, fmt.Errorf("name and description can not be set for %s", t.Method)
+	}
+	timeout := time.Duration(t.Timeout)
+	if timeout <= 0 {
+		timeout = time.Duration(cfg.Server.Timeout)
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	// metadata 按 key 排序，便于日志和测试；value 支持 ${ENV} 形式的环境变量
+	md := cfg.Upstreams[t.Upstream].Metadata
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		pairs = append(pairs, k, os.ExpandEnv(md[k]))
+	}
+
+	var routes []*route
+	for _, m := range methods {
+		r := &route{
+			name:        t.Name,
+			description: t.Description,
+			method:      m,
+			fullMethod:  "/" + string(m.Parent().FullName()) + "/" + string(m.Name()),
+			conn:        conn,
+			metadata:    pairs,
+			timeout:     timeout,
+			types:       desc.types,
+		}
+		if r.name == "" {
+			r.name = toolName(m.Name())
+		}
+		if r.description == "" {
+			r.description = comments(m)
+		}
+		if r.description == "" {
+			r.description = "Calls " + string(m.FullName())
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+// register 把所有 tool 注册到 mcp server
+func (a *adapter) register(s *server.MCPServer) {
+	for _, r := range a.routes {
+		s.AddTool(r.mcpTool(), r.handle)
+		log.Printf("Registered tool %s -> %s", r.name, r.fullMethod)
+	}
+}
+
+func (a *adapter) close() {
+	for _, conn := range a.conns {
+		conn.Close()
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// testProto 相当于下面的 users.proto，google/protobuf/timestamp.proto 不在 set 中，从 well-known types 中解析：
+//
+//	service Users {
+//	  // GetUser returns a user by id.
+//	  rpc GetUser(GetUserRequest) returns (User);
+//	  rpc CreateUser(User) returns (User);
+//	  rpc WatchUsers(GetUserRequest) returns (stream User);
+//	}
+const testProto = `
+name: "users.proto"
+package: "demo.users"
+syntax: "proto3"
+dependency: "google/protobuf/timestamp.proto"
+message_type {
+  name: "GetUserRequest"
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "id" }
+}
+message_type {
+  name: "User"
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "id" }
+  field { name: "display_name" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "displayName" }
+  field { name: "role" number: 3 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".demo.users.Role" json_name: "role" }
+  field { name: "tags" number: 4 label: LABEL_REPEATED type: TYPE_STRING json_name: "tags" }
+  field { name: "created_at" number: 5 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp" json_name: "createdAt" }
+  field { name: "manager" number: 6 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".demo.users.User" json_name: "manager" }
+}
+enum_type {
+  name: "Role"
+  value { name: "ROLE_UNSPECIFIED" number: 0 }
+  value { name: "ADMIN" number: 1 }
+}
+service {
+  name: "Users"
+  method { name: "GetUser" input_type: ".demo.users.GetUserRequest" output_type: ".demo.users.User" }
+  method { name: "CreateUser" input_type: ".demo.users.User" output_type: ".demo.users.User" }
+  method { name: "WatchUsers" input_type: ".demo.users.GetUserRequest" output_type: ".demo.users.User" server_streaming: true }
+}
+source_code_info {
+  location { path: [6, 0, 2, 0] span: [3, 2, 40] leading_comments: " GetUser returns a user by id.\n" }
+}
+`
+
+// writeDescriptorSet 把 testProto 写成 descriptor set 文件
+func writeDescriptorSet(t *testing.T) string {
+	var fd descriptorpb.FileDescriptorProto
+	if err := prototext.Unmarshal([]byte(testProto), &fd); err != nil {
+		t.Fatalf("failed to parse test proto: %v", err)
+	}
+	data, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{&fd}})
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "users.pb")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to write descriptor set: %v", err)
+	}
+	return path
+}
+
+// startUsersServer 启动进程内的 grpc 服务，用 dynamicpb 实现 Users 服务，并记录收到的 metadata
+func startUsersServer(t *testing.T, desc *descriptors, got *metadata.MD) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	message := func(name string) protoreflect.MessageDescriptor {
+		d, err := desc.files.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			t.Fatalf("message %s not found: %v", name, err)
+		}
+		return d.(protoreflect.MessageDescriptor)
+	}
+	s := grpc.NewServer(grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		*got, _ = metadata.FromIncomingContext(stream.Context())
+		user := message("demo.users.User")
+		var in *dynamicpb.Message
+		switch method {
+		case "/demo.users.Users/GetUser":
+			in = dynamicpb.NewMessage(message("demo.users.GetUserRequest"))
+		case "/demo.users.Users/CreateUser":
+			in = dynamicpb.NewMessage(user)
+		default:
+			return status.Errorf(codes.Unimplemented, "unknown method %s", method)
+		}
+		if err := stream.RecvMsg(in); err != nil {
+			return err
+		}
+		id := in.Get(in.Descriptor().Fields().ByName("id")).Int()
+		if id == 404 {
+			return status.Errorf(codes.NotFound, "user %d not found", id)
+		}
+		out := dynamicpb.NewMessage(user)
+		if method == "/demo.users.Users/CreateUser" {
+			out = in
+		} else {
+			fields := user.Fields()
+			out.Set(fields.ByName("id"), protoreflect.ValueOfInt64(id))
+			out.Set(fields.ByName("display_name"), protoreflect.ValueOfString("Ann"))
+			out.Set(fields.ByName("role"), protoreflect.ValueOfEnum(1))
+			out.Set(fields.ByName("created_at"), protoreflect.ValueOfMessage(timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)).ProtoReflect()))
+		}
+		return stream.SendMsg(out)
+	}))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func TestGRPCToolHandler(t *testing.T) {
+	path := writeDescriptorSet(t)
+	desc, err := loadDescriptors([]string{path})
+	if err != nil {
+		t.Fatalf("failed to load descriptor set: %v", err)
+	}
+	var got metadata.MD
+	target := startUsersServer(t, desc, &got)
+	t.Setenv("GRPC2MCP_TEST_TOKEN", "t1")
+
+	a, err := newAdapter(&Config{
+		DescriptorSets: []string{path},
+		Upstreams: map[string]UpstreamConfig{
+			"users": {Target: target, Metadata: map[string]string{"authorization": "Bearer ${GRPC2MCP_TEST_TOKEN}"}},
+			"down":  {Target: "127.0.0.1:1"},
+		},
+		Tools: []ToolConfig{
+			{Upstream: "users", Method: "demo.users.Users/*"},
+			{Name: "get_user_down", Upstream: "down", Method: "demo.users.Users/GetUser", Timeout: Duration(time.Second)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	defer a.close()
+
+	tests := []struct {
+		tool    string
+		args    map[string]any
+		isError bool
+		want    string
+	}{
+		{"get_user", map[string]any{"id": 7}, false, `{"id":"7","displayName":"Ann","role":"ADMIN","tags":[],"createdAt":"2024-01-02T03:04:05Z","manager":null}`},
+		{"create_user", map[string]any{"id": "8", "display_name": "Bob", "tags": []any{"a"}}, false, `{"id":"8","displayName":"Bob","role":"ROLE_UNSPECIFIED","tags":["a"],"createdAt":null,"manager":null}`},
+		{"get_user", map[string]any{"id": 404}, true, "grpc status NotFound: user 404 not found"},
+		{"get_user", map[string]any{"name": "x"}, true, `unknown field "name"`},
+		{"get_user_down", map[string]any{"id": 1}, true, "grpc status Unavailable"},
+	}
+	for _, tt := range tests {
+		var r *route
+		for _, candidate := range a.routes {
+			if candidate.name == tt.tool {
+				r = candidate
+			}
+		}
+		if r == nil {
+			t.Fatalf("tool %s not found", tt.tool)
+		}
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = tt.args
+		result, err := r.handle(context.Background(), request)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.tool, err)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		if result.IsError != tt.isError {
+			t.Errorf("%s %v: expected isError %v, got %q", tt.tool, tt.args, tt.isError, text)
+		}
+		if tt.isError && !strings.Contains(text, tt.want) {
+			t.Errorf("%s %v: expected %q, got %q", tt.tool, tt.args, tt.want, text)
+		}
+		if !tt.isError {
+			var gotJSON, wantJSON any
+			json.Unmarshal([]byte(text), &gotJSON)
+			json.Unmarshal([]byte(tt.want), &wantJSON)
+			if !reflect.DeepEqual(gotJSON, wantJSON) {
+				t.Errorf("%s: expected %s, got %s", tt.tool, tt.want, text)
+			}
+		}
+	}
+	if auth := got.Get("authorization"); len(auth) != 1 || auth[0] != "Bearer t1" {
+		t.Errorf("expected metadata to be forwarded, got %v", got)
+	}
+}
+
+func TestGRPCTools(t *testing.T) {
+	path := writeDescriptorSet(t)
+	a, err := newAdapter(&Config{
+		DescriptorSets: []string{path},
+		Upstreams:      map[string]UpstreamConfig{"users": {Target: "localhost:50051"}},
+		Tools:          []ToolConfig{{Upstream: "users", Method: "demo.users.Users/*"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	defer a.close()
+
+	// 流式方法不会暴露
+	if len(a.routes) != 2 || a.routes[0].name != "get_user" || a.routes[1].name != "create_user" {
+		t.Fatalf("unexpected routes %+v", a.routes)
+	}
+	if a.routes[0].description != "GetUser returns a user by id." || a.routes[1].description != "Calls demo.users.Users.CreateUser" {
+		t.Errorf("unexpected descriptions %q %q", a.routes[0].description, a.routes[1].description)
+	}
+
+	tool := a.routes[1].mcpTool()
+	props := tool.InputSchema.Properties
+	if !reflect.DeepEqual(props["id"], map[string]any{"type": []any{"integer", "string"}}) ||
+		!reflect.DeepEqual(props["role"], map[string]any{"type": "string", "enum": []any{"ROLE_UNSPECIFIED", "ADMIN"}}) ||
+		!reflect.DeepEqual(props["tags"], map[string]any{"type": "array", "items": map[string]any{"type": "string"}}) ||
+		!reflect.DeepEqual(props["createdAt"], map[string]any{"type": "string", "format": "date-time"}) {
+		t.Errorf("unexpected schema %v", props)
+	}
+	if manager := props["manager"].(map[string]any); manager["description"] != "Recursive demo.users.User message." {
+		t.Errorf("unexpected recursive schema %v", manager)
+	}
+
+	for _, tt := range []struct {
+		tool ToolConfig
+		want string
+	}{
+		{ToolConfig{Upstream: "users", Method: "demo.users.Users/WatchUsers"}, "only unary methods are supported"},
+		{ToolConfig{Upstream: "users", Method: "demo.users.Users/Nope"}, "method Nope not found"},
+		{ToolConfig{Upstream: "users", Method: "demo.users.Groups/Get"}, "service demo.users.Groups not found"},
+		{ToolConfig{Upstream: "other", Method: "demo.users.Users/GetUser"}, `unknown upstream "other"`},
+		{ToolConfig{Name: "x", Upstream: "users", Method: "demo.users.Users/*"}, "name and description can not be set"},
+	} {
+		_, err := newAdapter(&Config{
+			DescriptorSets: []string{path},
+			Upstreams:      map[string]UpstreamConfig{"users": {Target: "localhost:50051"}},
+			Tools:          []ToolConfig{tt.tool},
+		})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("expected error containing %q, got %v", tt.want, err)
+		}
+	}
+}
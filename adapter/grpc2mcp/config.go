@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"mcp-demo/internal/config"
+)
+
+// Config 是 grpc2mcp 的配置文件：从 descriptor set 中选出 unary 方法，每个方法暴露成一个 tool
+type Config struct {
+	Server ServerConfig `json:"server"`
+	// DescriptorSets 是 protoc --include_imports --descriptor_set_out 生成的 FileDescriptorSet 文件
+	DescriptorSets []string `json:"descriptorSets"`
+	// Upstreams 是按名称引用的 gRPC 服务
+	Upstreams map[string]UpstreamConfig `json:"upstreams"`
+	Tools     []ToolConfig              `json:"tools"`
+}
+
+type ServerConfig struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Addr 是 sse 服务监听的地址，如 :8092
+	Addr string `json:"addr"`
+	// Timeout 是 tool 没有单独配置 timeout 时一次调用的超时时间，默认 30s
+	Timeout Duration `json:"timeout"`
+}
+
+// UpstreamConfig 是一个 gRPC 服务的地址和连接方式
+type UpstreamConfig struct {
+	// Target 是 grpc.NewClient 的目标地址，如 localhost:50051 或 dns:///users.internal:443
+	Target string `json:"target"`
+	// TLS 为 true 时使用系统证书建立 TLS 连接，否则使用明文
+	TLS bool `json:"tls"`
+	// Metadata 在每次调用时发送，如 authorization，模型看不到；value 支持 ${ENV} 形式的环境变量
+	Metadata map[string]string `json:"metadata"`
+}
+
+// ToolConfig 把一个 unary 方法暴露成 tool
+type ToolConfig struct {
+	// Name 为空时由方法名生成，如 GetUser 生成 get_user
+	Name string `json:"name"`
+	// Description 为空时使用 proto 文件中方法的注释
+	Description string `json:"description"`
+	Upstream    string `json:"upstream"`
+	// Method 是 package.Service/Method，写成 package.Service/* 时暴露服务的所有 unary 方法
+	Method  string   `json:"method"`
+	Timeout Duration `json:"timeout"`
+}
+
+// loadConfig 读取 yaml 或 json 格式的配置文件
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	return parseConfig(data)
+}
+
+func parseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	if err := config.Decode(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %v", err)
+	}
+	if cfg.Server.Name == "" {
+		cfg.Server.Name = "grpc2mcp adapter"
+	}
+	if cfg.Server.Version == "" {
+		cfg.Server.Version = "1.0.0"
+	}
+	if cfg.Server.Addr == "" {
+		cfg.Server.Addr = ":8092"
+	}
+	return &cfg, nil
+}
+
+// Duration 是配置中的时长，可以写成 "30s"、"1m"，数字按秒处理
+type Duration = config.Duration
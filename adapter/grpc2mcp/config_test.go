@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseConfigYAML(t *testing.T) {
+	// yaml 中 1、true 这类 key 不是字符串，所在的 map 会被解析成 map[any]any，需要先转成字符串 key 才能按 json tag 解码
+	cfg, err := parseConfig([]byte(`
+server:
+  timeout: 5s
+descriptorSets: [users.pb]
+upstreams:
+  users:
+    target: localhost:50051
+    tls: true
+    metadata:
+      authorization: Bearer abc
+      1: shard
+      true: on
+tools:
+  - upstream: users
+    method: demo.users.Users/*
+    timeout: 30
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	if cfg.Server.Addr != ":8092" || time.Duration(cfg.Server.Timeout) != 5*time.Second {
+		t.Errorf("unexpected server config %+v", cfg.Server)
+	}
+	want := UpstreamConfig{Target: "localhost:50051", TLS: true, Metadata: map[string]string{"authorization": "Bearer abc", "1": "shard", "true": "on"}}
+	if !reflect.DeepEqual(cfg.Upstreams["users"], want) {
+		t.Errorf("expected upstream %+v, got %+v", want, cfg.Upstreams["users"])
+	}
+	if len(cfg.Tools) != 1 || time.Duration(cfg.Tools[0].Timeout) != 30*time.Second {
+		t.Errorf("unexpected tools %+v", cfg.Tools)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	// 注册 well-known types，descriptor set 没有用 --include_imports 生成时从这里解析
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// descriptors 是从 FileDescriptorSet 加载的所有 proto 文件
+type descriptors struct {
+	files *protoregistry.Files
+	// types 用于 protojson 解析 google.protobuf.Any 中的消息
+	types *dynamicpb.Types
+}
+
+// loadDescriptors 读取一个或多个 FileDescriptorSet 文件
+func loadDescriptors(paths []string) (*descriptors, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read descriptor set: %v", err)
+		}
+		var s descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("failed to parse descriptor set %s: %v", path, err)
+		}
+		set.File = append(set.File, s.File...)
+	}
+	return newDescriptors(set)
+}
+
+// newDescriptors 按依赖顺序构造文件描述，同名文件只保留第一个；依赖不在 set 中时从已注册的 well-known types 中查找
+func newDescriptors(set *descriptorpb.FileDescriptorSet) (*descriptors, error) {
+	protos := map[string]*descriptorpb.FileDescriptorProto{}
+	for _, fd := range set.File {
+		if _, ok := protos[fd.GetName()]; !ok {
+			protos[fd.GetName()] = fd
+		}
+	}
+	files := new(protoregistry.Files)
+	var build func(name string, stack []string) error
+	build = func(name string, stack []string) error {
+		if _, err := files.FindFileByPath(name); err == nil {
+			return nil
+		}
+		fd, ok := protos[name]
+		if !ok {
+			global, err := protoregistry.GlobalFiles.FindFileByPath(name)
+			if err != nil {
+				return fmt.Errorf("proto file %s is not in the descriptor set, generate it with --include_imports", name)
+			}
+			return files.RegisterFile(global)
+		}
+		for _, s := range stack {
+			if s == name {
+				return fmt.Errorf("import cycle: %s", strings.Join(append(stack, name), " -> "))
+			}
+		}
+		for _, dep := range fd.GetDependency() {
+			if err := build(dep, append(stack, name)); err != nil {
+				return err
+			}
+		}
+		file, err := protodesc.NewFile(fd, files)
+		if err != nil {
+			return fmt.Errorf("invalid proto file %s: %v", name, err)
+		}
+		return files.RegisterFile(file)
+	}
+	for _, fd := range set.File {
+		if err := build(fd.GetName(), nil); err != nil {
+			return nil, err
+		}
+	}
+	return &descriptors{files: files, types: dynamicpb.NewTypes(files)}, nil
+}
+
+// methods 按 package.Service/Method 查找方法，package.Service/* 返回服务的所有 unary 方法
+func (d *descriptors) methods(name string) ([]protoreflect.MethodDescriptor, error) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(name, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid method %q, expected package.Service/Method", name)
+	}
+	desc, err := d.files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found in descriptor sets", service)
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	if method == "*" {
+		var out []protoreflect.MethodDescriptor
+		for i := 0; i < sd.Methods().Len(); i++ {
+			if md := sd.Methods().Get(i); !md.IsStreamingClient() && !md.IsStreamingServer() {
+				out = append(out, md)
+			}
+		}
+		if len(out) == 0 {
+			return nil, fmt.Errorf("service %s has no unary methods", service)
+		}
+		return out, nil
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", method, service)
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, fmt.Errorf("method %s is a streaming method, only unary methods are supported", name)
+	}
+	return []protoreflect.MethodDescriptor{md}, nil
+}
+
+// comments 返回 proto 文件中的注释，descriptor set 需要用 --include_source_info 生成
+func comments(d protoreflect.Descriptor) string {
+	loc := d.ParentFile().SourceLocations().ByDescriptor(d)
+	return strings.TrimSpace(loc.LeadingComments)
+}
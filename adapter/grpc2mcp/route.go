@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// route 是一个 unary 方法对应的 tool，负责 json 入参与 protobuf 之间的转换
+type route struct {
+	name        string
+	description string
+	method      protoreflect.MethodDescriptor
+	// fullMethod 是 grpc 调用使用的 /package.Service/Method
+	fullMethod string
+	conn       grpc.ClientConnInterface
+	metadata   []string
+	timeout    time.Duration
+	types      *dynamicpb.Types
+}
+
+var upperWord = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// toolName 把 GetUser 这样的方法名转换成 get_user
+func toolName(method protoreflect.Name) string {
+	return strings.ToLower(upperWord.ReplaceAllString(string(method), "${1}_${2}"))
+}
+
+// mcpTool 生成 tool 定义，入参的 schema 由请求消息生成，见 schema.go
+func (r *route) mcpTool() mcp.Tool {
+	schema := messageSchema(r.method.Input(), map[protoreflect.FullName]bool{})
+	tool := mcp.NewTool(r.name, mcp.WithDescription(r.description))
+	tool.InputSchema.Properties, _ = schema["properties"].(map[string]any)
+	tool.InputSchema.Required, _ = schema["required"].([]string)
+	return tool
+}
+
+// handle 是转发用的 tool handler：json 入参 -> 请求消息 -> grpc 调用 -> 响应消息 -> json 结果
+func (r *route) handle(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	in := dynamicpb.NewMessage(r.method.Input())
+	if args := request.GetArguments(); len(args) > 0 {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode arguments: %v", err)
+		}
+		// 字段名或类型不对时作为 tool 错误告诉模型，不发到上游
+		if err := (protojson.UnmarshalOptions{Resolver: r.types}).Unmarshal(data, in); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid arguments: %v", err)), nil
+		}
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if len(r.metadata) > 0 {
+		callCtx = metadata.AppendToOutgoingContext(callCtx, r.metadata...)
+	}
+	out := dynamicpb.NewMessage(r.method.Output())
+	if err := r.conn.Invoke(callCtx, r.fullMethod, in, out); err != nil {
+		// 所有 grpc 状态码（包括连接失败的 Unavailable 和超时）都作为 tool 错误交给模型
+		return r.statusResult(err), nil
+	}
+
+	// 输出零值字段，模型能看到完整的响应结构
+	body, err := protojson.MarshalOptions{Resolver: r.types, EmitUnpopulated: true}.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %v", err)
+	}
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+// statusResult 把 grpc 状态转换成 tool 错误，如 "grpc status NotFound: user 42 not found"，
+// 状态中的 details 能按 descriptor set 解析时以 json 附在后面
+func (r *route) statusResult(err error) *mcp.CallToolResult {
+	st := status.Convert(err)
+	text := fmt.Sprintf("grpc status %s: %s", st.Code(), st.Message())
+	for _, detail := range st.Proto().GetDetails() {
+		if b, err := (protojson.MarshalOptions{Resolver: r.types}).Marshal(detail); err == nil {
+			text += "\ndetail: " + string(b)
+		} else {
+			text += "\ndetail: " + detail.GetTypeUrl()
+		}
+	}
+	return mcp.NewToolResultError(text)
+}
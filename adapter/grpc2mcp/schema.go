@@ -0,0 +1,126 @@
+package main
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// messageSchema 按 protojson 的映射规则把消息转换成 json schema：
+// 字段名使用 json_name（protojson 同时接受原始字段名），64 位整数和 bytes 以字符串表示，枚举使用名称，
+// well-known types 使用它们的 json 形式。seen 用于避免递归消息无限展开
+func messageSchema(md protoreflect.MessageDescriptor, seen map[protoreflect.FullName]bool) map[string]any {
+	if schema, ok := wellKnownSchema(md); ok {
+		return schema
+	}
+	if seen[md.FullName()] {
+		return map[string]any{"type": "object", "description": "Recursive " + string(md.FullName()) + " message."}
+	}
+	seen[md.FullName()] = true
+	defer delete(seen, md.FullName())
+
+	properties := map[string]any{}
+	var required []string
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		schema := fieldSchema(fd, seen)
+		description := comments(fd)
+		if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			description = joinSentences(description, "Only one field of "+string(oneof.Name())+" can be set.")
+		}
+		if description != "" {
+			schema["description"] = description
+		}
+		properties[fd.JSONName()] = schema
+		if fd.Cardinality() == protoreflect.Required {
+			required = append(required, fd.JSONName())
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fieldSchema 返回字段的 schema，repeated 字段是数组，map 字段是对象
+func fieldSchema(fd protoreflect.FieldDescriptor, seen map[protoreflect.FullName]bool) map[string]any {
+	switch {
+	case fd.IsMap():
+		return map[string]any{"type": "object", "additionalProperties": singularSchema(fd.MapValue(), seen)}
+	case fd.IsList():
+		return map[string]any{"type": "array", "items": singularSchema(fd, seen)}
+	}
+	return singularSchema(fd, seen)
+}
+
+func singularSchema(fd protoreflect.FieldDescriptor, seen map[protoreflect.FullName]bool) map[string]any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// protojson 把 64 位整数编码成字符串，解码时数字和字符串都接受
+		return map[string]any{"type": []any{"integer", "string"}}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return map[string]any{"type": "number"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		names := make([]any, values.Len())
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageSchema(fd.Message(), seen)
+	}
+	return map[string]any{}
+}
+
+// wellKnownSchema 返回 well-known types 的 json 形式
+func wellKnownSchema(md protoreflect.MessageDescriptor) (map[string]any, bool) {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return map[string]any{"type": "string", "format": "date-time"}, true
+	case "google.protobuf.Duration":
+		return map[string]any{"type": "string", "description": "Duration in seconds with an s suffix, such as 1.5s."}, true
+	case "google.protobuf.FieldMask":
+		return map[string]any{"type": "string", "description": "Comma-separated field paths."}, true
+	case "google.protobuf.Struct":
+		return map[string]any{"type": "object"}, true
+	case "google.protobuf.ListValue":
+		return map[string]any{"type": "array"}, true
+	case "google.protobuf.Value":
+		return map[string]any{}, true
+	case "google.protobuf.Empty":
+		return map[string]any{"type": "object", "properties": map[string]any{}}, true
+	case "google.protobuf.Any":
+		return map[string]any{"type": "object", "properties": map[string]any{"@type": map[string]any{"type": "string"}}, "required": []string{"@type"}}, true
+	case "google.protobuf.BoolValue":
+		return map[string]any{"type": "boolean"}, true
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return map[string]any{"type": "integer"}, true
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return map[string]any{"type": []any{"integer", "string"}}, true
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return map[string]any{"type": "number"}, true
+	case "google.protobuf.StringValue":
+		return map[string]any{"type": "string"}, true
+	case "google.protobuf.BytesValue":
+		return map[string]any{"type": "string", "contentEncoding": "base64"}, true
+	}
+	return nil, false
+}
+
+func joinSentences(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + " " + b
+}
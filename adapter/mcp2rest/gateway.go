@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-demo/internal/jsonschema"
+)
+
+// maxBodySize 是请求体的上限
+const maxBodySize = 4 << 20
+
+func main() {
+	stdio := flag.String("stdio", "", "以 stdio 方式启动的 mcp server 命令，如 'go run ../../mcp/server/stdio'")
+	sse := flag.String("sse", "", "mcp server 的 sse 地址，如 http://localhost:8090/sse")
+	streamable := flag.String("streamable", "", "mcp server 的 streamable http 地址，如 http://localhost:8090/mcp")
+	addr := flag.String("addr", ":8093", "rest 服务监听的地址")
+	refresh := flag.Duration("refresh", time.Minute, "定期刷新 tool 列表的间隔，0 表示只在收到 list_changed 通知时刷新")
+	headers := map[string]string{}
+	flag.Func("header", "连接 sse、streamable http 时携带的请求头，如 'Authorization: Bearer xxx'，可以重复", func(s string) error {
+		key, value, ok := strings.Cut(s, ":")
+		if !ok {
+			return fmt.Errorf("invalid header %q", s)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		return nil
+	})
+	flag.Parse()
+
+	ctx := context.Background()
+	c, err := connect(ctx, *stdio, *sse, *streamable, headers)
+	if err != nil {
+		log.Fatalf("Failed to connect to mcp server: %v", err)
+	}
+	defer c.Close()
+	g, err := newGateway(ctx, c)
+	if err != nil {
+		log.Fatalf("Failed to create gateway: %v", err)
+	}
+	go g.watch(ctx, *refresh)
+
+	log.Printf("REST gateway listening on %s, openapi document is http://localhost%s/openapi.json", *addr, *addr)
+	if err := http.ListenAndServe(*addr, g); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+}
+
+// connect 按参数选择 stdio、sse 或 streamable http 连接 mcp server，三者只能选一个
+func connect(ctx context.Context, stdio, sse, streamable string, headers map[string]string) (*client.Client, error) {
+	var (
+		c   *client.Client
+		err error
+		n   int
+	)
+	if stdio != "" {
+		n++
+		// 不用 NewStdioMCPClient：它创建时就启动了进程，再调用 Start 会启动第二个进程
+		fields := strings.Fields(stdio)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid stdio command %q", stdio)
+		}
+		c = client.NewClient(transport.NewStdio(fields[0], nil, fields[1:]...))
+	}
+	if sse != "" {
+		n++
+		c, err = client.NewSSEMCPClient(sse, client.WithHeaders(headers))
+	}
+	if streamable != "" {
+		n++
+		c, err = client.NewStreamableHttpClient(streamable, transport.WithHTTPHeaders(headers))
+	}
+	if n != 1 {
+		return nil, fmt.Errorf("exactly one of -stdio, -sse and -streamable is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Start 建立连接（stdio 启动进程）并接收 server 的通知
+	if err := c.Start(ctx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("failed to start client: %v", err)
+	}
+	return c, nil
+}
+
+// toolInfo 是 tools/list 中的一个 tool，schema 是 json 形式的 inputSchema
+type toolInfo struct {
+	name        string
+	description string
+	schema      map[string]any
+}
+
+// gateway 把 mcp server 的 tools 以 rest 接口提供：POST /tools/{name} 调用 tool，GET /openapi.json 返回接口文档。
+// tool 列表在收到 notifications/tools/list_changed 或定期刷新时更新
+type gateway struct {
+	client *client.Client
+	info   mcp.Implementation
+	// changed 合并刷新请求，通知回调中不能直接发请求，否则 stdio 会阻塞在读取响应上
+	changed chan struct{}
+
+	mu      sync.RWMutex
+	tools   map[string]toolInfo
+	openapi []byte
+}
+
+// newGateway 完成 mcp 初始化并读取一次 tool 列表
+func newGateway(ctx context.Context, c *client.Client) (*gateway, error) {
+	g := &gateway{client: c, changed: make(chan struct{}, 1)}
+	c.OnNotification(func(n mcp.JSONRPCNotification) {
+		if n.Method == string(mcp.MethodNotificationToolsListChanged) {
+			g.notify()
+		}
+	})
+
+	req := mcp.InitializeRequest{}
+	req.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	req.Params.ClientInfo = mcp.Implementation{Name: "mcp2rest", Version: "1.0.0"}
+	result, err := c.Initialize(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize: %v", err)
+	}
+	g.info = result.ServerInfo
+	if err := g.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *gateway) notify() {
+	select {
+	case g.changed <- struct{}{}:
+	default:
+	}
+}
+
+// watch 在收到变更通知或每隔 interval 刷新 tool 列表，直到 ctx 结束
+func (g *gateway) watch(ctx context.Context, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-g.changed:
+		case <-tick:
+		}
+		if err := g.refresh(ctx); err != nil {
+			log.Printf("Failed to refresh tools: %v", err)
+		}
+	}
+}
+
+// refresh 重新读取 tool 列表并生成 OpenAPI 文档
+func (g *gateway) refresh(ctx context.Context) error {
+	result, err := g.client.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to list tools: %v", err)
+	}
+	tools := make(map[string]toolInfo, len(result.Tools))
+	list := make([]toolInfo, 0, len(result.Tools))
+	for _, t := range result.Tools {
+		info, err := newToolInfo(t)
+		if err != nil {
+			return err
+		}
+		tools[t.Name] = info
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	doc, err := json.MarshalIndent(openAPIDocument(g.info.Name, g.info.Version, list), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode openapi document: %v", err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(tools) != len(g.tools) || !bytes.Equal(doc, g.openapi) {
+		log.Printf("Loaded %d tools from %s", len(tools), g.info.Name)
+	}
+	g.tools, g.openapi = tools, doc
+	return nil
+}
+
+// newToolInfo 取出 json 形式的 inputSchema，无论 server 返回的是结构化的 schema 还是原始 schema
+func newToolInfo(t mcp.Tool) (toolInfo, error) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return toolInfo{}, fmt.Errorf("failed to encode tool %s: %v", t.Name, err)
+	}
+	var raw struct {
+		InputSchema map[string]any `json:"inputSchema"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return toolInfo{}, fmt.Errorf("failed to decode schema of tool %s: %v", t.Name, err)
+	}
+	if raw.InputSchema == nil {
+		raw.InputSchema = map[string]any{"type": "object"}
+	}
+	return toolInfo{name: t.Name, description: t.Description, schema: raw.InputSchema}, nil
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/openapi.json" && r.Method == http.MethodGet:
+		g.mu.RLock()
+		doc := g.openapi
+		g.mu.RUnlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	case strings.HasPrefix(r.URL.Path, "/tools/"):
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
+			return
+		}
+		g.callTool(w, r, strings.TrimPrefix(r.URL.Path, "/tools/"))
+	default:
+		writeError(w, http.StatusNotFound, "not found", nil)
+	}
+}
+
+// callTool 校验请求体并调用 tool：请求体不符合 inputSchema 返回 400，tool 返回错误时返回 422，连不上 mcp server 返回 502
+func (g *gateway) callTool(w http.ResponseWriter, r *http.Request, name string) {
+	g.mu.RLock()
+	tool, ok := g.tools[name]
+	g.mu.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("tool %s not found", name), nil)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("failed to read request body: %v", err), nil)
+		return
+	}
+	// 空请求体等同于 {}
+	args := map[string]any{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		var body any
+		if err := json.Unmarshal(data, &body); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid json body: %v", err), nil)
+			return
+		}
+		if args, ok = body.(map[string]any); !ok {
+			writeError(w, http.StatusBadRequest, "request body must be a json object", nil)
+			return
+		}
+	}
+	if errs := jsonschema.Validate(tool.schema, args, "body", jsonschema.Options{}); len(errs) > 0 {
+		writeError(w, http.StatusBadRequest, "request body does not match the input schema of "+name, errs)
+		return
+	}
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+	result, err := g.client.CallTool(r.Context(), req)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error(), nil)
+		return
+	}
+	if result.IsError {
+		writeError(w, http.StatusUnprocessableEntity, resultText(result), nil)
+		return
+	}
+	writeResult(w, result)
+}
+
+// writeResult 写入 tool 结果：单个 json 文本原样返回，单个文本、图片或音频按各自的类型返回，其他情况返回完整的 tool 结果
+func writeResult(w http.ResponseWriter, result *mcp.CallToolResult) {
+	if len(result.Content) == 1 {
+		switch c := result.Content[0].(type) {
+		case mcp.TextContent:
+			if json.Valid([]byte(c.Text)) {
+				w.Header().Set("Content-Type", "application/json")
+			} else {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			}
+			io.WriteString(w, c.Text)
+			return
+		case mcp.ImageContent:
+			if writeBinary(w, c.MIMEType, c.Data) {
+				return
+			}
+		case mcp.AudioContent:
+			if writeBinary(w, c.MIMEType, c.Data) {
+				return
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeBinary(w http.ResponseWriter, mimeType, data string) bool {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return false
+	}
+	w.Header().Set("Content-Type", mimeType)
+	w.Write(b)
+	return true
+}
+
+// resultText 拼接 tool 错误结果中的文本
+func resultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, c := range result.Content {
+		if t, ok := c.(mcp.TextContent); ok {
+			parts = append(parts, t.Text)
+		}
+	}
+	if len(parts) == 0 {
+		return "tool returned an error"
+	}
+	return strings.Join(parts, "\n")
+}
+
+func writeError(w http.ResponseWriter, status int, message string, details []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	body := map[string]any{"error": message}
+	if len(details) > 0 {
+		body["details"] = details
+	}
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// stdioEnv 为 1 时测试程序作为 stdio mcp server 运行，供 stdio 传输的测试启动
+const stdioEnv = "MCP2REST_TEST_STDIO"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(stdioEnv) == "1" {
+		if err := server.ServeStdio(newTestMCPServer()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+
+// newTestMCPServer 返回测试用的 mcp server，包含返回 json、文本、图片和错误的 tool
+func newTestMCPServer() *server.MCPServer {
+	s := server.NewMCPServer("test tools", "0.1.0", server.WithToolCapabilities(true))
+	s.AddTool(mcp.NewTool("add",
+		mcp.WithDescription("Adds two numbers. Returns the sum."),
+		mcp.WithNumber("a", mcp.Required()),
+		mcp.WithNumber("b", mcp.Required()),
+	), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+		return mcp.NewToolResultText(fmt.Sprintf(`{"sum":%v}`, args["a"].(float64)+args["b"].(float64))), nil
+	})
+	s.AddTool(mcp.NewTool("greet",
+		mcp.WithString("name", mcp.Required(), mcp.MinLength(1)),
+		mcp.WithString("lang", mcp.Enum("en", "zh")),
+	), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("Hello, " + req.GetString("name", "")), nil
+	})
+	s.AddTool(mcp.NewTool("fail"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultError("upstream is down"), nil
+	})
+	s.AddTool(mcp.NewTool("logo"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewImageContent(base64.StdEncoding.EncodeToString(testPNG), "image/png")}}, nil
+	})
+	return s
+}
+
+// startGateway 通过指定的传输连接 mcp server，返回 gateway 和它的 rest 服务
+func startGateway(t *testing.T, stdio, sse, streamable string) (*gateway, *httptest.Server) {
+	c, err := connect(context.Background(), stdio, sse, streamable, nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	g, err := newGateway(context.Background(), c)
+	if err != nil {
+		t.Fatalf("failed to create gateway: %v", err)
+	}
+	rest := httptest.NewServer(g)
+	t.Cleanup(rest.Close)
+	return g, rest
+}
+
+func post(t *testing.T, url, body string) (int, string, string) {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to post %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header.Get("Content-Type"), string(b)
+}
+
+func TestGateway(t *testing.T) {
+	transports := []struct {
+		name    string
+		connect func(t *testing.T) (*gateway, *httptest.Server)
+	}{
+		{"stdio", func(t *testing.T) (*gateway, *httptest.Server) {
+			t.Setenv(stdioEnv, "1")
+			return startGateway(t, os.Args[0]+" -test.run=^$", "", "")
+		}},
+		{"sse", func(t *testing.T) (*gateway, *httptest.Server) {
+			mcpServer := server.NewTestServer(newTestMCPServer())
+			// 先注册的 cleanup 后执行，sse 连接断开后再关闭 server
+			t.Cleanup(mcpServer.Close)
+			return startGateway(t, "", mcpServer.URL+"/sse", "")
+		}},
+		{"streamable", func(t *testing.T) (*gateway, *httptest.Server) {
+			mcpServer := server.NewTestStreamableHTTPServer(newTestMCPServer())
+			t.Cleanup(mcpServer.Close)
+			return startGateway(t, "", "", mcpServer.URL+"/mcp")
+		}},
+	}
+	for _, tr := range transports {
+		t.Run(tr.name, func(t *testing.T) {
+			_, rest := tr.connect(t)
+			tests := []struct {
+				tool, body  string
+				status      int
+				contentType string
+				want        string
+			}{
+				{"add", `{"a": 1, "b": 2}`, 200, "application/json", `{"sum":3}`},
+				{"greet", `{"name": "Ann"}`, 200, "text/plain; charset=utf-8", "Hello, Ann"},
+				{"logo", ``, 200, "image/png", string(testPNG)},
+				{"fail", `{}`, 422, "application/json", `{"error":"upstream is down"}`},
+				{"add", `{"a": "1"}`, 400, "application/json", `{"details":["body.b: is required","body.a: expected number, got string"],"error":"request body does not match the input schema of add"}`},
+				{"greet", `{"name": "", "lang": "fr"}`, 400, "application/json", `{"details":["body.lang: must be one of [\"en\",\"zh\"]","body.name: must be at least 1 characters"],"error":"request body does not match the input schema of greet"}`},
+				{"add", `[1, 2]`, 400, "application/json", `{"error":"request body must be a json object"}`},
+				{"nope", `{}`, 404, "application/json", `{"error":"tool nope not found"}`},
+			}
+			for _, tt := range tests {
+				status, contentType, body := post(t, rest.URL+"/tools/"+tt.tool, tt.body)
+				if status != tt.status || contentType != tt.contentType || strings.TrimSpace(body) != tt.want {
+					t.Errorf("%s %s: expected %d %s %s, got %d %s %s", tt.tool, tt.body, tt.status, tt.contentType, tt.want, status, contentType, body)
+				}
+			}
+
+			resp, err := http.Get(rest.URL + "/tools/add")
+			if err != nil {
+				t.Fatalf("failed to get: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusMethodNotAllowed {
+				t.Errorf("expected 405, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+// openAPIPaths 返回 /openapi.json 中的所有 path
+func openAPIPaths(t *testing.T, rest *httptest.Server) map[string]any {
+	resp, err := http.Get(rest.URL + "/openapi.json")
+	if err != nil {
+		t.Fatalf("failed to get openapi document: %v", err)
+	}
+	defer resp.Body.Close()
+	var doc struct {
+		Info  map[string]any `json:"info"`
+		Paths map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("failed to decode openapi document: %v", err)
+	}
+	if doc.Info["title"] != "test tools" || doc.Info["version"] != "0.1.0" {
+		t.Errorf("unexpected info %v", doc.Info)
+	}
+	return doc.Paths
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	s := newTestMCPServer()
+	mcpServer := server.NewTestServer(s)
+	t.Cleanup(mcpServer.Close)
+	g, rest := startGateway(t, "", mcpServer.URL+"/sse", "")
+
+	paths := openAPIPaths(t, rest)
+	if len(paths) != 4 {
+		t.Fatalf("expected 4 paths, got %v", paths)
+	}
+	add := paths["/tools/add"].(map[string]any)["post"].(map[string]any)
+	if add["operationId"] != "add" || add["summary"] != "Adds two numbers." || add["description"] != "Adds two numbers. Returns the sum." {
+		t.Errorf("unexpected operation %v", add)
+	}
+	body := add["requestBody"].(map[string]any)
+	schema := body["content"].(map[string]any)["application/json"].(map[string]any)["schema"]
+	want := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"a": map[string]any{"type": "number"}, "b": map[string]any{"type": "number"}},
+		"required":   []any{"a", "b"},
+	}
+	if body["required"] != true || !reflect.DeepEqual(schema, want) {
+		t.Errorf("unexpected request body %v", body)
+	}
+
+	// tool 列表变化后 server 发送 list_changed 通知，文档随之更新
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go g.watch(ctx, 0)
+	s.AddTool(mcp.NewTool("late", mcp.WithDescription("Added later")), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("late"), nil
+	})
+	s.DeleteTools("fail")
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		paths = openAPIPaths(t, rest)
+		_, late := paths["/tools/late"]
+		_, fail := paths["/tools/fail"]
+		if late && !fail {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("openapi document was not updated: %v", paths)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if status, _, body := post(t, rest.URL+"/tools/late", ""); status != 200 || body != "late" {
+		t.Errorf("unexpected result of new tool: %d %s", status, body)
+	}
+	if status, _, _ := post(t, rest.URL+"/tools/fail", "{}"); status != 404 {
+		t.Errorf("expected deleted tool to return 404, got %d", status)
+	}
+}
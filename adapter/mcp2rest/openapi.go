@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+
+	"mcp-demo/internal/jsonschema"
+)
+
+// errorSchema 是所有错误响应的格式
+var errorSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"error":   map[string]any{"type": "string"},
+		"details": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	},
+	"required": []string{"error"},
+}
+
+// openAPIDocument 按 tools/list 的结果生成 OpenAPI 3.1 文档，每个 tool 一个 POST /tools/{name}，
+// 请求体的 schema 就是 tool 的 inputSchema
+func openAPIDocument(title, version string, tools []toolInfo) map[string]any {
+	errorResponse := func(description string) map[string]any {
+		return map[string]any{
+			"description": description,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}},
+			},
+		}
+	}
+	paths := map[string]any{}
+	for _, t := range tools {
+		op := map[string]any{
+			"operationId": t.name,
+			"summary":     summary(t.description, t.name),
+			"requestBody": map[string]any{
+				"required": len(jsonschema.StringList(t.schema["required"])) > 0,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": t.schema},
+				},
+			},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "Tool result. A single json text result is returned as is, other single text, image or audio results are returned with their own content type, and anything else as the MCP tool result.",
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": map[string]any{}},
+						"text/plain":       map[string]any{"schema": map[string]any{"type": "string"}},
+					},
+				},
+				"400": errorResponse("The request body does not match the input schema."),
+				"404": errorResponse("The tool does not exist."),
+				"422": errorResponse("The tool returned an error."),
+				"502": errorResponse("The MCP server could not be reached."),
+			},
+		}
+		if t.description != "" {
+			op["description"] = t.description
+		}
+		paths["/tools/"+t.name] = map[string]any{"post": op}
+	}
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info":    map[string]any{"title": title, "version": version},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": map[string]any{"Error": errorSchema},
+		},
+	}
+}
+
+// summary 取描述的第一句，没有描述时使用 tool 名称
+func summary(description, name string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(description), "\n")
+	if line == "" {
+		return name
+	}
+	if i := strings.Index(line, ". "); i >= 0 {
+		line = line[:i+1]
+	}
+	return line
+}
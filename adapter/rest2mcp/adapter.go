@@ -1,81 +1,251 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"errors"
+	"flag"
 	"fmt"
-	"github.com/mark3labs/mcp-go/mcp"
-	"github.com/mark3labs/mcp-go/server"
-	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
 )
 
 func main() {
-	s := server.NewMCPServer(
-		"MCP Server with SSE",
-		"1.0.0",
-	)
+	// curl 子命令：把 curl 命令转换成 tool 配置后退出
+	if len(os.Args) > 1 && os.Args[1] == "curl" {
+		if err := curlCommand(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Failed to convert curl command: %v", err)
+		}
+		return
+	}
 
-	// Add greetTool
-	greetTool := mcp.NewTool("hello_world",
-		mcp.WithDescription("Say hello to someone"),
-		mcp.WithString("name",
-			mcp.Required(),
-			mcp.Description("Name of the person to greet"),
-		),
-	)
+	configFile := flag.String("config", "adapter.yaml", "tool 与 rest 接口映射的配置文件（json 或 yaml）")
+	openapiFile := flag.String("openapi", "", "OpenAPI 3 文档路径（json 或 yaml），为每个 operation 生成一个 tool")
+	postmanFile := flag.String("postman", "", "Postman v2.1 集合路径，为每个请求生成一个 tool")
+	harFile := flag.String("har", "", "HAR 文件路径，为每个接口生成一个 tool")
+	graphqlFile := flag.String("graphql", "", "GraphQL introspection 结果（json）路径，为 Query 和 Mutation 的每个字段生成一个 tool，-base-url 为 GraphQL 接口地址")
+	baseURL := flag.String("base-url", "", "上游 rest 服务地址，默认使用 OpenAPI 文档中的第一个 servers.url 或 Postman、HAR 中的地址")
+	openapiUpstream := flag.String("openapi-upstream", "", "OpenAPI、Postman、HAR、GraphQL 生成的 tool 使用配置文件中的哪个上游（地址和认证）")
+	dryRun := flag.Bool("dry-run", false, "所有 tool 只返回构造好的 http 请求而不发送，等同于配置 server.dryRun")
+	adminAddr := flag.String("admin-addr", "", "管理接口单独监听的地址，如 127.0.0.1:8094，覆盖 server.adminAddr，为空时不提供管理接口")
+	flag.Parse()
 
-	// Add greetTool handler
-	s.AddTool(greetTool, helloHandler)
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// 从 OpenAPI 文档、Postman 集合、HAR 文件和 GraphQL schema 生成 tools
+	importers := []struct {
+		name string
+		file string
+		load func(path, baseURL, upstream string) ([]ToolConfig, error)
+	}{
+		{"openapi", *openapiFile, loadOpenAPITools},
+		{"postman collection", *postmanFile, loadPostmanTools},
+		{"har", *harFile, loadHARTools},
+		{"graphql schema", *graphqlFile, loadGraphQLTools},
+	}
+	for _, im := range importers {
+		if im.file == "" {
+			continue
+		}
+		tools, err := im.load(im.file, *baseURL, *openapiUpstream)
+		if err != nil {
+			log.Fatalf("Failed to load %s: %v", im.name, err)
+		}
+		cfg.Tools = append(cfg.Tools, tools...)
+	}
+	if *dryRun {
+		cfg.Server.DryRun = true
+	}
+	if cfg.Server.DryRun {
+		log.Printf("Dry run: tools return the requests without sending them")
+	}
+	if *adminAddr != "" {
+		cfg.Server.AdminAddr = *adminAddr
+	}
+	if cfg.Server.AdminAddr != "" && cfg.Server.AdminAddr == cfg.Server.Addr {
+		log.Fatalf("Admin address %s must differ from the server address", cfg.Server.AdminAddr)
+	}
+
+	a, err := newAdapter(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	s := server.NewMCPServer(
+		cfg.Server.Name,
+		cfg.Server.Version,
+		// 流式 tool 通过日志通知转发上游的数据块
+		server.WithLogging(),
+		server.WithHooks(a.hooks()),
+	)
+	// 按配置注册 tools，新增上游接口只需要修改配置文件
+	a.register(s)
+	// 健康检查在后台运行直到进程退出
+	a.start(context.Background())
 
 	//Start the sse server
-	port := ":8090"
+	port := cfg.Server.Addr
 	baseUrl := "http://localhost" + port + "/"
 	log.Printf("baseUrl is : %s", baseUrl)
-	sseServer := server.NewSSEServer(s, server.WithBaseURL(baseUrl))
-	log.Printf("SSE server listening on : %s", port)
-	if err := sseServer.Start(port); err != nil {
+	// 两种传输都从请求头中读取会话的上游凭证
+	sseServer := server.NewSSEServer(s, server.WithBaseURL(baseUrl), server.WithSSEContextFunc(a.sessionContext))
+
+	// 管理接口没有认证，单独监听，不与 mcp 服务共用端口
+	if addr := cfg.Server.AdminAddr; addr != "" {
+		go func() {
+			log.Printf("Admin server listening on : %s", addr)
+			if err := http.ListenAndServe(addr, a.adminHandler()); err != nil {
+				log.Fatalf("Admin server error: %v", err)
+			}
+		}()
+	}
+
+	// streamable http 与 sse 服务共用端口
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", server.NewStreamableHTTPServer(s, server.WithHTTPContextFunc(a.sessionContext)))
+	mux.Handle("/", sseServer)
+	log.Printf("SSE server listening on : %s, streamable http endpoint is %smcp", port, baseUrl)
+	if err := http.ListenAndServe(port, mux); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
 
-func helloHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	name, ok := request.Params.Arguments.(map[string]any)["name"].(string)
-	if !ok {
-		return nil, errors.New("name must be a string")
+// adapter 持有共用的 http client、所有上游和 tool 路由
+type adapter struct {
+	client    *http.Client
+	breakers  *breakerSet
+	upstreams map[string]*upstream
+	routes    []*route
+	resources []*resource
+	sessions  *sessionStore
+	// explain 为 true 时为每个 tool 注册 <tool>__explain
+	explain bool
+}
+
+// newAdapter 校验配置并构造上游和路由，不会发起任何网络请求
+func newAdapter(cfg *Config) (*adapter, error) {
+	a := &adapter{
+		client:    newHTTPClient(cfg.Server.Client),
+		breakers:  newBreakerSet(),
+		upstreams: map[string]*upstream{},
+		sessions:  newSessionStore(),
+		explain:   cfg.Server.Explain,
+	}
+	for name, uc := range cfg.Upstreams {
+		u, err := newUpstream(name, uc, a.client)
+		if err != nil {
+			return nil, err
+		}
+		a.upstreams[name] = u
 	}
 
-	// 构造请求体
-	reqBody, err := json.Marshal(map[string]string{"name": name})
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %v", err)
+	names := map[string]bool{}
+	for i, t := range cfg.Tools {
+		if t.Name == "" {
+			return nil, fmt.Errorf("tools[%d]: name is required", i)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("tool %s: duplicate name", t.Name)
+		}
+		if t.Name == setCredentialsTool && len(a.sessionAuthUpstreams()) > 0 {
+			return nil, fmt.Errorf("tool %s: name is reserved for session credentials", t.Name)
+		}
+		names[t.Name] = true
+		if a.explain {
+			for _, other := range cfg.Tools {
+				if other.Name == t.Name+explainSuffix {
+					return nil, fmt.Errorf("tool %s: name is reserved for explaining tool %s", other.Name, t.Name)
+				}
+			}
+		}
+		if t.RequestTemplate.URL == "" {
+			return nil, fmt.Errorf("tool %s: requestTemplate.url is required", t.Name)
+		}
+		r, err := a.newRoute(t, cfg.Server)
+		if err != nil {
+			return nil, err
+		}
+		a.routes = append(a.routes, r)
 	}
 
-	// 发送 HTTP 请求到 /greet
-	resp, err := http.Post("http://localhost:8091/greet", "application/json", bytes.NewBuffer(reqBody))
+	uris := map[string]bool{}
+	for i, rc := range cfg.Resources {
+		if rc.Name == "" || rc.URI == "" {
+			return nil, fmt.Errorf("resources[%d]: name and uri are required", i)
+		}
+		if uris[rc.URI] {
+			return nil, fmt.Errorf("resource %s: duplicate uri %s", rc.Name, rc.URI)
+		}
+		uris[rc.URI] = true
+		res, err := a.newResource(rc, cfg.Server)
+		if err != nil {
+			return nil, err
+		}
+		a.resources = append(a.resources, res)
+	}
+	return a, nil
+}
+
+// newRoute 构造路由并接入共用的 http client、熔断器和默认超时
+func (a *adapter) newRoute(t ToolConfig, sc ServerConfig) (*route, error) {
+	r, err := newRoute(t, a.upstreams)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call /greet endpoint: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	r.client = a.client
+	r.breakers = a.breakers
+	r.readResource = a.readResource
+	r.sessions = a.sessions
+	r.breakerConfig = sc.Breaker
+	r.dryRun = sc.DryRun
+	if r.upstream != nil && r.upstream.breaker != nil {
+		r.breakerConfig = *r.upstream.breaker
+	}
+	if t.Timeout == 0 && sc.Client.Timeout > 0 {
+		r.timeout = time.Duration(sc.Client.Timeout)
+	}
+	if t.ResponseTemplate.MaxSize == 0 && sc.MaxResponseSize > 0 {
+		r.maxSize = sc.MaxResponseSize
+	}
+	return r, nil
+}
+
+// adminHandler 返回管理接口，查看熔断器、上游实例和缓存的状态
+func (a *adapter) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/admin/breakers", a.breakers)
+	mux.HandleFunc("/admin/upstreams", a.upstreamsHandler)
+	mux.HandleFunc("/admin/cache", a.cacheHandler)
+	return mux
+}
 
-	// 检查响应状态码
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("received non-200 response: %d, body: %s", resp.StatusCode, string(body))
+// start 启动上游实例的健康检查，与 newAdapter 分开以便只校验配置时不产生后台任务
+func (a *adapter) start(ctx context.Context) {
+	for _, u := range a.upstreams {
+		u.startHealthChecks(ctx, a.client)
 	}
+}
 
-	// 解析响应体
-	var greetResp struct {
-		Message string `json:"message"`
+// register 把所有 tool 和 resource 注册到 mcp server
+func (a *adapter) register(s *server.MCPServer) {
+	for _, r := range a.routes {
+		s.AddTool(r.mcpTool(), r.handle)
+		log.Printf("Registered tool %s -> %s %s", r.Name, r.RequestTemplate.Method, r.RequestTemplate.URL)
+		if a.explain {
+			s.AddTool(r.explainTool(), r.handleExplain)
+		}
 	}
-	err = json.NewDecoder(resp.Body).Decode(&greetResp)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode response body: %v", err)
+	for _, res := range a.resources {
+		res.register(s)
+		log.Printf("Registered resource %s -> %s %s", res.URI, res.route.RequestTemplate.Method, res.route.RequestTemplate.URL)
+	}
+	if len(a.sessionAuthUpstreams()) > 0 {
+		s.AddTool(a.credentialsTool(), a.setCredentials)
+		log.Printf("Registered tool %s for upstreams %v", setCredentialsTool, a.sessionAuthUpstreams())
 	}
-
-	// 返回包装后的结果
-	return mcp.NewToolResultText(greetResp.Message), nil
 }
@@ -28,7 +28,7 @@ func TestAdapter(t *testing.T) {
 	time.Sleep(2 * time.Second)
 
 	// 启动 adapter.go 的 MCP 服务器
-	cmd := exec.Command("go", "run", "adapter.go")
+	cmd := exec.Command("go", "run", ".")
 	if err := cmd.Start(); err != nil {
 		t.Fatalf("Failed to start adapter server: %v", err)
 	}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// AsyncConfig 描述异步接口：上游返回 202 Accepted 和 Location 后，轮询 Location 直到任务结束
+type AsyncConfig struct {
+	// Interval 是轮询间隔，默认 1s，上游返回 Retry-After 时以它为准
+	Interval Duration `json:"interval"`
+	// Deadline 是从提交任务起等待结果的最长时间，默认 5m
+	Deadline Duration `json:"deadline"`
+	// Status 是 JSONPath，从 2xx 的轮询响应中取任务状态；不配置时 202 表示未完成，其余 2xx 即为最终结果
+	Status string `json:"status"`
+	// Pending 和 Failed 是表示未完成和失败的状态值，其余状态视为完成
+	Pending []string `json:"pending"`
+	Failed  []string `json:"failed"`
+	// Progress 是 JSONPath，从轮询响应中取进度；不配置时以轮询次数作为进度
+	Progress string `json:"progress"`
+	// Total 是进度的总量，如 100
+	Total float64 `json:"total"`
+	// CancelOnAbort 为 true 时，调用被取消或超过 deadline 后向任务地址发送 DELETE
+	CancelOnAbort bool `json:"cancelOnAbort"`
+}
+
+// asyncPoller 是解析好的 AsyncConfig
+type asyncPoller struct {
+	AsyncConfig
+	status   *jsonPath
+	progress *jsonPath
+}
+
+func newAsyncPoller(toolName string, cfg *AsyncConfig) (*asyncPoller, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	p := &asyncPoller{AsyncConfig: *cfg}
+	if p.Interval <= 0 {
+		p.Interval = Duration(time.Second)
+	}
+	if p.Deadline <= 0 {
+		p.Deadline = Duration(5 * time.Minute)
+	}
+	var err error
+	if cfg.Status != "" {
+		if p.status, err = compileJSONPath(cfg.Status); err != nil {
+			return nil, fmt.Errorf("tool %s: async.status: %v", toolName, err)
+		}
+	}
+	if cfg.Progress != "" {
+		if p.progress, err = compileJSONPath(cfg.Progress); err != nil {
+			return nil, fmt.Errorf("tool %s: async.progress: %v", toolName, err)
+		}
+	}
+	return p, nil
+}
+
+// poll 轮询任务地址直到任务结束，期间按轮询结果向客户端发送 notifications/progress
+func (r *route) poll(ctx context.Context, request mcp.CallToolRequest, accepted *upstreamResponse, args map[string]any) (*mcp.CallToolResult, error) {
+	p := r.async
+	jobURL, err := location(accepted)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if jobURL == "" {
+		// 没有给出任务地址，只能把 202 的响应体作为结果
+		return r.buildResult(accepted)
+	}
+
+	pollCtx, cancel := context.WithTimeout(ctx, time.Duration(p.Deadline))
+	defer cancel()
+	resp := accepted
+	for n := 1; ; n++ {
+		wait := time.Duration(p.Interval)
+		if resp != nil {
+			if secs, err := strconv.Atoi(resp.header.Get("Retry-After")); err == nil {
+				wait = time.Duration(secs) * time.Second
+			}
+		}
+		select {
+		case <-pollCtx.Done():
+			r.abandonJob(jobURL)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return mcp.NewToolResultError(fmt.Sprintf("job %s did not finish within %s", jobURL, time.Duration(p.Deadline))), nil
+		case <-time.After(wait):
+		}
+
+		reqCtx, cancelReq := context.WithTimeout(pollCtx, r.timeout)
+		var err error
+		resp, err = r.fetch(reqCtx, http.MethodGet, jobURL)
+		cancelReq()
+		if err != nil {
+			// 网络错误时继续轮询，直到 deadline
+			if pollCtx.Err() == nil {
+				log.Printf("tool %s: failed to poll %s: %v", r.Name, jobURL, err)
+			}
+			continue
+		}
+		if resp.status == http.StatusAccepted {
+			next, err := location(resp)
+			if err != nil {
+				r.abandonJob(jobURL)
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			if next != "" {
+				jobURL = next
+			}
+			p.notify(ctx, request, n, resp, "")
+			continue
+		}
+		if resp.status < 200 || resp.status > 299 {
+			return r.errorResult(resp.status, resp.body, args)
+		}
+		if p.status == nil {
+			return r.buildResult(resp)
+		}
+
+		state := evalString(p.status, resp.body)
+		switch {
+		case slices.Contains(p.Pending, state):
+			p.notify(ctx, request, n, resp, state)
+		case slices.Contains(p.Failed, state):
+			return mcp.NewToolResultError(fmt.Sprintf("job %s failed: %s\nbody: %s", jobURL, state, truncate(string(resp.body), maxErrorBody))), nil
+		default:
+			return r.buildResult(resp)
+		}
+	}
+}
+
+// notify 发送进度通知，客户端没有提供 progressToken 时不发送
+func (p *asyncPoller) notify(ctx context.Context, request mcp.CallToolRequest, n int, resp *upstreamResponse, message string) {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return
+	}
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return
+	}
+	params := map[string]any{"progressToken": request.Params.Meta.ProgressToken, "progress": float64(n)}
+	if p.progress != nil {
+		if v, ok := evalValue(p.progress, resp.body).(float64); ok {
+			params["progress"] = v
+		}
+	}
+	if p.Total > 0 {
+		params["total"] = p.Total
+	}
+	if message != "" {
+		params["message"] = message
+	}
+	if err := srv.SendNotificationToClient(ctx, "notifications/progress", params); err != nil {
+		log.Printf("failed to send progress notification: %v", err)
+	}
+}
+
+// abandonJob 在放弃等待时取消上游任务，调用方的 ctx 已经结束，所以使用新的 context
+func (r *route) abandonJob(jobURL string) {
+	if !r.async.CancelOnAbort {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	resp, err := r.fetch(ctx, http.MethodDelete, jobURL)
+	if err != nil {
+		log.Printf("tool %s: failed to cancel job %s: %v", r.Name, jobURL, err)
+		return
+	}
+	log.Printf("tool %s: cancelled job %s: %d", r.Name, jobURL, resp.status)
+}
+
+// fetch 向任务地址发送不带请求体的请求，同样注入上游认证信息
+func (r *route) fetch(ctx context.Context, method, rawURL string) (*upstreamResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if err := r.authenticate(ctx, req); err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s %s: %v", method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response of %s %s: %v", method, req.URL.Path, err)
+	}
+	return &upstreamResponse{status: resp.StatusCode, header: resp.Header, body: body, url: rawURL}, nil
+}
+
+// location 取响应中的 Location，相对地址按请求地址解析，没有 Location 时返回空字符串。
+// 轮询和取消任务时会注入上游的认证信息，所以与翻页一样只允许与请求相同的 scheme 和 host
+func location(resp *upstreamResponse) (string, error) {
+	loc := resp.header.Get("Location")
+	if loc == "" {
+		return "", nil
+	}
+	base, err := url.Parse(resp.url)
+	if err != nil {
+		return "", fmt.Errorf("invalid request url %q: %v", resp.url, err)
+	}
+	ref, err := url.Parse(loc)
+	if err != nil {
+		return "", fmt.Errorf("invalid job location %q: %v", loc, err)
+	}
+	jobURL := base.ResolveReference(ref)
+	if jobURL.Scheme != base.Scheme || jobURL.Host != base.Host {
+		return "", fmt.Errorf("invalid job location %q: it does not belong to %s", loc, base.Host)
+	}
+	return jobURL.String(), nil
+}
+
+// evalValue 在 json 响应体上执行 JSONPath，响应体不是 json 或没有选中时返回 nil
+func evalValue(p *jsonPath, body []byte) any {
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil
+	}
+	v, _ := p.eval(data)
+	return v
+}
+
+func evalString(p *jsonPath, body []byte) string {
+	v := evalValue(p, body)
+	if v == nil {
+		return ""
+	}
+	return stringify(v)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func newJobServer(t *testing.T, finishAfter int32) (*httptest.Server, *atomic.Int32, *atomic.Bool) {
+	var polls atomic.Int32
+	var deleted atomic.Bool
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/reports":
+			w.Header().Set("Location", "/jobs/1")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodDelete && r.URL.Path == "/jobs/1":
+			deleted.Store(true)
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/jobs/1":
+			n := polls.Add(1)
+			if n < finishAfter {
+				fmt.Fprintf(w, `{"state":"running","pct":%d}`, n*30)
+				return
+			}
+			w.Write([]byte(`{"state":"done","pct":100,"result":"report ready"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s, &polls, &deleted
+}
+
+func newAsyncRoute(t *testing.T, baseURL string, async string) *route {
+	cfg, err := parseConfig([]byte(`
+upstreams:
+  reports:
+    baseURL: ` + baseURL + `
+tools:
+  - name: create_report
+    requestTemplate: {upstream: reports, url: /reports, method: POST}
+    responseTemplate: {select: $.result}
+    async: ` + async + `
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	return a.routes[0]
+}
+
+func TestAsyncPolling(t *testing.T) {
+	upstream, polls, _ := newJobServer(t, 3)
+	r := newAsyncRoute(t, upstream.URL, `{interval: 10ms, status: $.state, pending: [running], failed: [failed], progress: $.pct, total: 100}`)
+
+	s := server.NewMCPServer("test", "1.0.0")
+	s.AddTool(r.mcpTool(), r.handle)
+	sse := server.NewTestServer(s)
+	defer sse.Close()
+
+	c, err := client.NewSSEMCPClient(sse.URL + "/sse")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+	var mu sync.Mutex
+	var progress []float64
+	c.OnNotification(func(n mcp.JSONRPCNotification) {
+		if n.Method == "notifications/progress" {
+			mu.Lock()
+			progress = append(progress, n.Params.AdditionalFields["progress"].(float64))
+			mu.Unlock()
+		}
+	})
+	ctx := context.Background()
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("failed to start client: %v", err)
+	}
+	if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "create_report"
+	request.Params.Meta = &mcp.Meta{ProgressToken: "report-1"}
+	result, err := c.CallTool(ctx, request)
+	if err != nil {
+		t.Fatalf("failed to call tool: %v", err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; result.IsError || text != "report ready" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if polls.Load() != 3 {
+		t.Errorf("expected 3 polls, got %d", polls.Load())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(progress) != "[30 60]" {
+		t.Errorf("unexpected progress notifications %v", progress)
+	}
+}
+
+func TestAsyncDeadline(t *testing.T) {
+	upstream, _, deleted := newJobServer(t, 1000)
+	r := newAsyncRoute(t, upstream.URL, `{interval: 10ms, deadline: 50ms, status: $.state, pending: [running], cancelOnAbort: true}`)
+
+	result, err := r.handle(context.Background(), mcp.CallToolRequest{})
+	if err != nil {
+		t.Fatalf("expected tool error, got protocol error: %v", err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "did not finish within 50ms") {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if !deleted.Load() {
+		t.Error("expected job to be cancelled with DELETE")
+	}
+
+	// 客户端取消调用时同样取消任务
+	deleted.Store(false)
+	r = newAsyncRoute(t, upstream.URL, `{interval: 10ms, status: $.state, pending: [running], cancelOnAbort: true}`)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := r.handle(ctx, mcp.CallToolRequest{}); err == nil {
+		t.Error("expected error after cancellation")
+	}
+	if !deleted.Load() {
+		t.Error("expected job to be cancelled with DELETE")
+	}
+}
+
+func TestAsyncForeignLocation(t *testing.T) {
+	// 任务地址指向其他 host 时不轮询，避免把上游凭证发过去
+	var leaked atomic.Int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked.Add(1)
+	}))
+	defer other.Close()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/reports":
+			w.Header().Set("Location", "/jobs/1")
+		case "/jobs/1":
+			w.Header().Set("Location", other.URL+"/jobs/2")
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer upstream.Close()
+
+	for _, first := range []string{other.URL + "/jobs/1", "/jobs/1"} {
+		r := newAsyncRoute(t, upstream.URL, `{interval: 10ms, cancelOnAbort: true}`)
+		r.upstream.auth = &bearerAuth{token: "s3cret"}
+		accepted := &upstreamResponse{status: http.StatusAccepted, header: http.Header{"Location": {first}}, url: upstream.URL + "/reports"}
+		result, err := r.poll(context.Background(), mcp.CallToolRequest{}, accepted, nil)
+		if err != nil {
+			t.Fatalf("expected tool error, got protocol error: %v", err)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "does not belong to") {
+			t.Errorf("%s: unexpected result %+v", first, result)
+		}
+	}
+	if n := leaked.Load(); n != 0 {
+		t.Errorf("expected no requests to the foreign host, got %d", n)
+	}
+}
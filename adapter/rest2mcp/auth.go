@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 上游认证方式
+const (
+	authAPIKey = "apiKey"
+	authBearer = "bearer"
+	authBasic  = "basic"
+	authOAuth2 = "oauth2"
+)
+
+// AuthConfig 描述调用上游时注入的认证信息，所有字符串字段都支持 ${ENV} 形式的环境变量，
+// 凭证只在 adapter 内部使用，不会出现在 tool 的入参和结果中
+type AuthConfig struct {
+	Type string `json:"type"`
+
+	// apiKey：In 为 header（默认）或 query，Name 为 header 名或 query 参数名
+	In    string `json:"in"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+
+	// bearer
+	Token string `json:"token"`
+
+	// basic
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// oauth2 client credentials
+	TokenURL     string   `json:"tokenURL"`
+	ClientID     string   `json:"clientID"`
+	ClientSecret string   `json:"clientSecret"`
+	Scopes       []string `json:"scopes"`
+	// EndpointParams 是请求 token 时额外携带的表单参数，如 audience
+	EndpointParams map[string]string `json:"endpointParams"`
+	// RefreshBefore 指定 token 过期前多久刷新，默认 1m
+	RefreshBefore Duration `json:"refreshBefore"`
+}
+
+// authenticator 在请求发出前注入认证信息
+type authenticator interface {
+	apply(ctx context.Context, req *http.Request) error
+}
+
+func newAuthenticator(cfg *AuthConfig) (authenticator, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	switch cfg.Type {
+	case authAPIKey:
+		a := &apiKeyAuth{in: cfg.In, name: os.ExpandEnv(cfg.Name), value: os.ExpandEnv(cfg.Value)}
+		if a.in == "" {
+			a.in = positionHeader
+		}
+		if a.in != positionHeader && a.in != positionQuery {
+			return nil, fmt.Errorf("apiKey auth: in must be header or query, got %q", a.in)
+		}
+		if a.name == "" {
+			return nil, fmt.Errorf("apiKey auth: name is required")
+		}
+		return a, nil
+	case authBearer:
+		return &bearerAuth{token: os.ExpandEnv(cfg.Token)}, nil
+	case authBasic:
+		return &basicAuth{username: os.ExpandEnv(cfg.Username), password: os.ExpandEnv(cfg.Password)}, nil
+	case authOAuth2:
+		if cfg.TokenURL == "" {
+			return nil, fmt.Errorf("oauth2 auth: tokenURL is required")
+		}
+		a := &oauth2Auth{
+			tokenURL:      os.ExpandEnv(cfg.TokenURL),
+			clientID:      os.ExpandEnv(cfg.ClientID),
+			clientSecret:  os.ExpandEnv(cfg.ClientSecret),
+			scopes:        cfg.Scopes,
+			params:        map[string]string{},
+			refreshBefore: time.Duration(cfg.RefreshBefore),
+			client:        http.DefaultClient,
+			now:           time.Now,
+		}
+		for k, v := range cfg.EndpointParams {
+			a.params[k] = os.ExpandEnv(v)
+		}
+		if a.refreshBefore == 0 {
+			a.refreshBefore = time.Minute
+		}
+		return a, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth type %q", cfg.Type)
+	}
+}
+
+type apiKeyAuth struct {
+	in, name, value string
+}
+
+func (a *apiKeyAuth) apply(_ context.Context, req *http.Request) error {
+	if a.in == positionQuery {
+		q := req.URL.Query()
+		q.Set(a.name, a.value)
+		req.URL.RawQuery = q.Encode()
+		return nil
+	}
+	req.Header.Set(a.name, a.value)
+	return nil
+}
+
+type bearerAuth struct {
+	token string
+}
+
+func (a *bearerAuth) apply(_ context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	return nil
+}
+
+type basicAuth struct {
+	username, password string
+}
+
+func (a *basicAuth) apply(_ context.Context, req *http.Request) error {
+	req.SetBasicAuth(a.username, a.password)
+	return nil
+}
+
+// oauth2Auth 实现 client credentials 模式，token 缓存到过期前 refreshBefore 再刷新
+type oauth2Auth struct {
+	tokenURL      string
+	clientID      string
+	clientSecret  string
+	scopes        []string
+	params        map[string]string
+	refreshBefore time.Duration
+	client        *http.Client
+	now           func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (a *oauth2Auth) apply(ctx context.Context, req *http.Request) error {
+	// dry run 时不请求 token 接口，反正 token 会被遮盖
+	if isDryRun(ctx) {
+		req.Header.Set("Authorization", "Bearer "+maskedValue)
+		return nil
+	}
+	token, err := a.getToken(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// invalidate 丢弃缓存的 token，上游返回 401 时调用，下次请求会重新获取
+func (a *oauth2Auth) invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+}
+
+func (a *oauth2Auth) getToken(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && (a.expires.IsZero() || a.now().Before(a.expires.Add(-a.refreshBefore))) {
+		return a.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.scopes) > 0 {
+		form.Set("scope", strings.Join(a.scopes, " "))
+	}
+	for k, v := range a.params {
+		form.Set(k, v)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(a.clientID), url.QueryEscape(a.clientSecret))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch oauth2 token: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read oauth2 token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch oauth2 token: status %d, body: %s", resp.StatusCode, truncate(string(body), 512))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", fmt.Errorf("failed to decode oauth2 token response: %v", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("oauth2 token response has no access_token")
+	}
+	a.token = tokenResp.AccessToken
+	// 没有 expires_in 时认为 token 不过期，直到上游返回 401
+	a.expires = time.Time{}
+	if tokenResp.ExpiresIn > 0 {
+		a.expires = a.now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+	return a.token, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestStaticAuth(t *testing.T) {
+	t.Setenv("TEST_API_KEY", "secret-key")
+
+	tests := []struct {
+		name  string
+		auth  AuthConfig
+		check func(r *http.Request) bool
+	}{
+		{
+			name:  "api key header",
+			auth:  AuthConfig{Type: "apiKey", Name: "X-API-Key", Value: "${TEST_API_KEY}"},
+			check: func(r *http.Request) bool { return r.Header.Get("X-API-Key") == "secret-key" },
+		},
+		{
+			name: "api key query",
+			auth: AuthConfig{Type: "apiKey", In: "query", Name: "key", Value: "${TEST_API_KEY}"},
+			check: func(r *http.Request) bool {
+				return r.URL.Query().Get("key") == "secret-key" && r.URL.Query().Get("q") == "1"
+			},
+		},
+		{
+			name:  "bearer",
+			auth:  AuthConfig{Type: "bearer", Token: "${TEST_API_KEY}"},
+			check: func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer secret-key" },
+		},
+		{
+			name: "basic",
+			auth: AuthConfig{Type: "basic", Username: "user", Password: "${TEST_API_KEY}"},
+			check: func(r *http.Request) bool {
+				u, p, ok := r.BasicAuth()
+				return ok && u == "user" && p == "secret-key"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := newAuthenticator(&tt.auth)
+			if err != nil {
+				t.Fatalf("failed to create authenticator: %v", err)
+			}
+			req, _ := http.NewRequest(http.MethodGet, "http://localhost/x?q=1", nil)
+			if err := a.apply(context.Background(), req); err != nil {
+				t.Fatalf("failed to apply auth: %v", err)
+			}
+			if !tt.check(req) {
+				t.Errorf("unexpected request %s %v", req.URL, req.Header)
+			}
+		})
+	}
+
+	for _, cfg := range []AuthConfig{{Type: "apiKey"}, {Type: "apiKey", Name: "k", In: "cookie"}, {Type: "oauth2"}, {Type: "digest"}} {
+		if _, err := newAuthenticator(&cfg); err == nil {
+			t.Errorf("%+v: expected error", cfg)
+		}
+	}
+}
+
+func TestOAuth2Auth(t *testing.T) {
+	// 本地模拟的 token 服务
+	var issued atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if r.FormValue("grant_type") != "client_credentials" || id != "client" || secret != "s3cret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		n := issued.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d-%s-%s", n, r.FormValue("scope"), r.FormValue("audience")),
+			"token_type":   "Bearer",
+			"expires_in":   120,
+		})
+	}))
+	defer tokenServer.Close()
+
+	// 上游只接受最新的 token
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := fmt.Sprintf("Bearer token-%d-read write-orders", issued.Load())
+		if r.Header.Get("Authorization") != want {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer upstreamServer.Close()
+
+	cfg, err := parseConfig([]byte(fmt.Sprintf(`
+upstreams:
+  orders:
+    baseURL: %s/api
+    auth:
+      type: oauth2
+      tokenURL: %s/token
+      clientID: client
+      clientSecret: s3cret
+      scopes: [read, write]
+      endpointParams:
+        audience: orders
+      refreshBefore: 30s
+tools:
+  - name: list_orders
+    requestTemplate:
+      upstream: orders
+      url: /orders
+`, upstreamServer.URL, tokenServer.URL)))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	r := a.routes[0]
+	auth := r.upstream.auth.(*oauth2Auth)
+	now := time.Now()
+	auth.now = func() time.Time { return now }
+
+	call := func() (*mcp.CallToolResult, error) {
+		return r.handle(context.Background(), mcp.CallToolRequest{})
+	}
+
+	// 第一次调用获取 token，之后复用缓存
+	for i := 0; i < 3; i++ {
+		if _, err := call(); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+	if n := issued.Load(); n != 1 {
+		t.Errorf("expected 1 token request, got %d", n)
+	}
+
+	// 距离过期不足 refreshBefore 时刷新
+	now = now.Add(95 * time.Second)
+	if _, err := call(); err != nil {
+		t.Fatalf("call after refresh failed: %v", err)
+	}
+	if n := issued.Load(); n != 2 {
+		t.Errorf("expected token to be refreshed, got %d token requests", n)
+	}
+
+	// 上游返回 401 后丢弃缓存的 token
+	issued.Add(1)
+	if result, err := call(); err != nil || !result.IsError {
+		t.Fatalf("expected call with revoked token to return a tool error, got %v", err)
+	}
+	if result, err := call(); err != nil {
+		t.Fatalf("call after 401 failed: %v", err)
+	} else if got := result.Content[0].(mcp.TextContent).Text; got != "Bearer token-4-read write-orders" {
+		t.Errorf("unexpected upstream response %q", got)
+	}
+}
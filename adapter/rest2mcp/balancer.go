@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+// 负载均衡策略
+const (
+	balanceRoundRobin     = "roundRobin"
+	balanceLeastInFlight  = "leastInFlight"
+	balanceConsistentHash = "consistentHash"
+)
+
+// LoadBalanceConfig 描述如何在上游的多个实例之间分配请求
+type LoadBalanceConfig struct {
+	// Policy 为 roundRobin（默认）、leastInFlight 或 consistentHash
+	Policy string `json:"policy"`
+	// HashArg 是 consistentHash 使用的 tool 入参，同一个值总是落到同一个实例上；参数缺失时按 roundRobin 处理
+	HashArg string `json:"hashArg"`
+}
+
+// instance 是上游的一个实例
+type instance struct {
+	baseURL  string
+	healthy  atomic.Bool
+	inFlight atomic.Int64
+}
+
+func newInstance(baseURL string) *instance {
+	inst := &instance{baseURL: baseURL}
+	inst.healthy.Store(true)
+	return inst
+}
+
+type balancer interface {
+	pick(args map[string]any) *instance
+}
+
+func newBalancer(cfg LoadBalanceConfig, instances []*instance) (balancer, error) {
+	rr := &roundRobin{instances: instances}
+	switch cfg.Policy {
+	case "", balanceRoundRobin:
+		return rr, nil
+	case balanceLeastInFlight:
+		return &leastInFlight{instances: instances}, nil
+	case balanceConsistentHash:
+		if cfg.HashArg == "" {
+			return nil, fmt.Errorf("loadBalance.hashArg is required for consistentHash")
+		}
+		return newHashRing(cfg.HashArg, instances, rr), nil
+	default:
+		return nil, fmt.Errorf("unsupported load balance policy %q", cfg.Policy)
+	}
+}
+
+// candidates 返回健康的实例，全部不健康时返回所有实例，避免健康检查误判导致完全不可用
+func candidates(instances []*instance) []*instance {
+	healthy := make([]*instance, 0, len(instances))
+	for _, inst := range instances {
+		if inst.healthy.Load() {
+			healthy = append(healthy, inst)
+		}
+	}
+	if len(healthy) == 0 {
+		return instances
+	}
+	return healthy
+}
+
+type roundRobin struct {
+	instances []*instance
+	next      atomic.Uint64
+}
+
+func (b *roundRobin) pick(map[string]any) *instance {
+	list := candidates(b.instances)
+	return list[(b.next.Add(1)-1)%uint64(len(list))]
+}
+
+type leastInFlight struct {
+	instances []*instance
+}
+
+func (b *leastInFlight) pick(map[string]any) *instance {
+	var best *instance
+	for _, inst := range candidates(b.instances) {
+		if best == nil || inst.inFlight.Load() < best.inFlight.Load() {
+			best = inst
+		}
+	}
+	return best
+}
+
+// hashRing 是带虚拟节点的一致性哈希环，实例下线时只有落在它上面的 key 会迁移
+type hashRing struct {
+	arg      string
+	points   []uint32
+	owners   map[uint32]*instance
+	fallback balancer
+}
+
+const hashReplicas = 100
+
+func newHashRing(arg string, instances []*instance, fallback balancer) *hashRing {
+	ring := &hashRing{arg: arg, owners: map[uint32]*instance{}, fallback: fallback}
+	for _, inst := range instances {
+		for i := 0; i < hashReplicas; i++ {
+			p := crc32.ChecksumIEEE([]byte(inst.baseURL + "#" + strconv.Itoa(i)))
+			if _, ok := ring.owners[p]; ok {
+				continue
+			}
+			ring.owners[p] = inst
+			ring.points = append(ring.points, p)
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+	return ring
+}
+
+func (ring *hashRing) pick(args map[string]any) *instance {
+	value, ok := args[ring.arg]
+	if !ok || value == nil {
+		return ring.fallback.pick(args)
+	}
+	h := crc32.ChecksumIEEE([]byte(stringify(value)))
+	start := sort.Search(len(ring.points), func(i int) bool { return ring.points[i] >= h })
+	// 顺时针找到第一个健康的实例
+	for i := 0; i < len(ring.points); i++ {
+		inst := ring.owners[ring.points[(start+i)%len(ring.points)]]
+		if inst.healthy.Load() {
+			return inst
+		}
+	}
+	return ring.owners[ring.points[start%len(ring.points)]]
+}
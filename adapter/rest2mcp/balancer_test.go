@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// newInstances 启动 n 个上游，返回它们的地址以及每个上游收到的请求数
+func newInstances(t *testing.T, n int) ([]string, []*atomic.Int32, []*atomic.Bool) {
+	var urls []string
+	var calls []*atomic.Int32
+	var down []*atomic.Bool
+	for i := 0; i < n; i++ {
+		c, d := &atomic.Int32{}, &atomic.Bool{}
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if d.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if r.URL.Path == "/healthz" {
+				return
+			}
+			c.Add(1)
+			w.Write([]byte(r.Host))
+		}))
+		t.Cleanup(s.Close)
+		urls = append(urls, s.URL)
+		calls = append(calls, c)
+		down = append(down, d)
+	}
+	return urls, calls, down
+}
+
+func newBalancedAdapter(t *testing.T, urls []string, lb LoadBalanceConfig, hc *HealthCheckConfig) *adapter {
+	a, err := newAdapter(&Config{
+		Upstreams: map[string]UpstreamConfig{"svc": {BaseURLs: urls, LoadBalance: lb, HealthCheck: hc}},
+		Tools: []ToolConfig{{
+			Name:            "get_order",
+			Args:            []ArgConfig{{Name: "id", Type: "string", Position: positionQuery}},
+			RequestTemplate: RequestTemplate{Upstream: "svc", URL: "/orders"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	return a
+}
+
+func call(t *testing.T, r *route, args map[string]any) string {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = args
+	result, err := r.handle(context.Background(), request)
+	if err != nil {
+		t.Fatalf("failed to call tool: %v", err)
+	}
+	return result.Content[0].(mcp.TextContent).Text
+}
+
+func TestRoundRobin(t *testing.T) {
+	urls, calls, _ := newInstances(t, 3)
+	r := newBalancedAdapter(t, urls, LoadBalanceConfig{}, nil).routes[0]
+	for i := 0; i < 9; i++ {
+		call(t, r, nil)
+	}
+	for i, c := range calls {
+		if c.Load() != 3 {
+			t.Errorf("instance %d: expected 3 calls, got %d", i, c.Load())
+		}
+	}
+}
+
+func TestLeastInFlight(t *testing.T) {
+	urls, _, _ := newInstances(t, 3)
+	u := newBalancedAdapter(t, urls, LoadBalanceConfig{Policy: balanceLeastInFlight}, nil).upstreams["svc"]
+	u.instances[0].inFlight.Store(2)
+	u.instances[1].inFlight.Store(1)
+	u.instances[2].inFlight.Store(3)
+	if inst := u.pick(nil); inst != u.instances[1] {
+		t.Errorf("expected least loaded instance %s, got %s", u.instances[1].baseURL, inst.baseURL)
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	urls, _, _ := newInstances(t, 3)
+	a := newBalancedAdapter(t, urls, LoadBalanceConfig{Policy: balanceConsistentHash, HashArg: "id"}, nil)
+	r, u := a.routes[0], a.upstreams["svc"]
+
+	owners := map[string]string{}
+	for _, id := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		owners[id] = call(t, r, map[string]any{"id": id})
+		for i := 0; i < 3; i++ {
+			if host := call(t, r, map[string]any{"id": id}); host != owners[id] {
+				t.Fatalf("id %s: expected sticky instance %s, got %s", id, owners[id], host)
+			}
+		}
+	}
+
+	// 摘除一个实例后，只有原本落在它上面的 key 会迁移
+	removed := u.instances[0]
+	removed.healthy.Store(false)
+	for id, owner := range owners {
+		host := call(t, r, map[string]any{"id": id})
+		if "http://"+host == removed.baseURL {
+			t.Errorf("id %s: routed to unhealthy instance", id)
+		}
+		if "http://"+owner != removed.baseURL && host != owner {
+			t.Errorf("id %s: moved from %s to %s although its instance is healthy", id, owner, host)
+		}
+	}
+
+	if _, err := newAdapter(&Config{
+		Upstreams: map[string]UpstreamConfig{"svc": {BaseURLs: urls, LoadBalance: LoadBalanceConfig{Policy: balanceConsistentHash}}},
+	}); err == nil {
+		t.Error("expected error for consistentHash without hashArg")
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	urls, calls, down := newInstances(t, 2)
+	a := newBalancedAdapter(t, urls, LoadBalanceConfig{}, &HealthCheckConfig{
+		Path:               "/healthz",
+		Interval:           Duration(10 * time.Millisecond),
+		UnhealthyThreshold: 1,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a.start(ctx)
+	u := a.upstreams["svc"]
+
+	waitFor := func(cond func() bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for health check")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// 第一个实例故障后被摘除，请求全部落到第二个实例
+	down[0].Store(true)
+	waitFor(func() bool { return !u.instances[0].healthy.Load() })
+	for i := 0; i < 4; i++ {
+		call(t, a.routes[0], nil)
+	}
+	if calls[0].Load() != 0 || calls[1].Load() != 4 {
+		t.Errorf("expected all calls on healthy instance, got %d and %d", calls[0].Load(), calls[1].Load())
+	}
+
+	rec := httptest.NewRecorder()
+	a.adminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/upstreams", nil))
+	var statuses []instanceStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("failed to decode admin response: %v", err)
+	}
+	unhealthy := 0
+	for _, s := range statuses {
+		if !s.Healthy {
+			unhealthy++
+		}
+	}
+	if len(statuses) != 2 || unhealthy != 1 {
+		t.Errorf("unexpected upstream status %s", rec.Body.String())
+	}
+
+	// 恢复后重新加入
+	down[0].Store(false)
+	waitFor(func() bool { return u.instances[0].healthy.Load() })
+}
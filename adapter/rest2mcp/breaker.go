@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 熔断器状态
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// BreakerConfig 描述上游熔断策略。连续失败（网络错误或 5xx）达到 FailureThreshold 次后熔断，
+// 熔断期间的调用直接返回 tool 错误；OpenTimeout 后进入半开状态，放行 HalfOpenRequests 个试探请求，
+// 全部成功则恢复，任意一个失败则重新熔断
+type BreakerConfig struct {
+	// FailureThreshold 为 0 时不启用熔断
+	FailureThreshold int      `json:"failureThreshold"`
+	OpenTimeout      Duration `json:"openTimeout"`
+	HalfOpenRequests int      `json:"halfOpenRequests"`
+}
+
+// breakerOpenError 表示上游处于熔断状态，请求没有发出
+type breakerOpenError struct {
+	host  string
+	retry time.Duration
+}
+
+func (e *breakerOpenError) Error() string {
+	return fmt.Sprintf("upstream %s is unavailable (circuit breaker open), retry after %s", e.host, e.retry.Round(time.Second))
+}
+
+type breaker struct {
+	host string
+	cfg  BreakerConfig
+	now  func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	// generation 在每次状态变化时加一，结束时状态已经变化的请求不再计入
+	generation int
+	inFlight   int
+	successes  int
+}
+
+// breakerTicket 记录请求被放行时熔断器的状态，probe 表示占用了半开状态的试探名额
+type breakerTicket struct {
+	generation int
+	probe      bool
+}
+
+func newBreaker(host string, cfg BreakerConfig) *breaker {
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = Duration(30 * time.Second)
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	return &breaker{host: host, cfg: cfg, now: time.Now, state: breakerClosed}
+}
+
+// allow 判断请求能否发出，放行时调用方必须在请求结束后用返回的 ticket 调用 record 或 abandon
+func (b *breaker) allow() (breakerTicket, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerOpen {
+		wait := time.Duration(b.cfg.OpenTimeout) - b.now().Sub(b.openedAt)
+		if wait > 0 {
+			return breakerTicket{}, &breakerOpenError{host: b.host, retry: wait}
+		}
+		b.setState(breakerHalfOpen)
+		b.inFlight, b.successes = 0, 0
+	}
+	if b.state == breakerHalfOpen {
+		if b.inFlight >= b.cfg.HalfOpenRequests {
+			return breakerTicket{}, &breakerOpenError{host: b.host, retry: time.Second}
+		}
+		b.inFlight++
+		return breakerTicket{generation: b.generation, probe: true}, nil
+	}
+	return breakerTicket{generation: b.generation}, nil
+}
+
+// record 记录请求结果，ok 为 false 表示网络错误或 5xx。
+// 放行之后熔断器状态已经变化的请求不计入，如关闭时放行、半开时才结束的请求不算试探
+func (b *breaker) record(t breakerTicket, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t.generation != b.generation {
+		return
+	}
+	switch b.state {
+	case breakerHalfOpen:
+		b.inFlight--
+		if !ok {
+			b.trip()
+			return
+		}
+		if b.successes++; b.successes >= b.cfg.HalfOpenRequests {
+			b.setState(breakerClosed)
+			b.failures = 0
+		}
+	case breakerClosed:
+		if ok {
+			b.failures = 0
+			return
+		}
+		if b.failures++; b.failures >= b.cfg.FailureThreshold {
+			b.trip()
+		}
+	}
+}
+
+// abandon 释放 allow 占用的名额但不记录结果，用于调用方主动取消的请求
+func (b *breaker) abandon(t breakerTicket) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t.probe && t.generation == b.generation {
+		b.inFlight--
+	}
+}
+
+func (b *breaker) trip() {
+	b.setState(breakerOpen)
+	b.openedAt = b.now()
+	b.failures = 0
+}
+
+func (b *breaker) setState(state string) {
+	b.state = state
+	b.generation++
+}
+
+// breakerStatus 是管理接口返回的熔断器状态
+type breakerStatus struct {
+	Host string `json:"host"`
+	// FailureThreshold 区分同一个 host 上策略不同的熔断器
+	FailureThreshold int        `json:"failureThreshold"`
+	State            string     `json:"state"`
+	Failures         int        `json:"failures"`
+	OpenedAt         *time.Time `json:"openedAt,omitempty"`
+}
+
+func (b *breaker) status() breakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := breakerStatus{Host: b.host, FailureThreshold: b.cfg.FailureThreshold, State: b.state, Failures: b.failures}
+	if b.state != breakerClosed {
+		openedAt := b.openedAt
+		s.OpenedAt = &openedAt
+	}
+	return s
+}
+
+// breakerKey 区分同一个 host 上熔断策略不同的上游
+type breakerKey struct {
+	host string
+	cfg  BreakerConfig
+}
+
+// breakerSet 按上游 host 和熔断策略维护熔断器
+type breakerSet struct {
+	mu       sync.Mutex
+	breakers map[breakerKey]*breaker
+}
+
+func newBreakerSet() *breakerSet {
+	return &breakerSet{breakers: map[breakerKey]*breaker{}}
+}
+
+// get 返回 host 和 cfg 对应的熔断器，cfg 未启用熔断时返回 nil
+func (s *breakerSet) get(host string, cfg BreakerConfig) *breaker {
+	if s == nil || cfg.FailureThreshold <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := breakerKey{host: host, cfg: cfg}
+	b, ok := s.breakers[key]
+	if !ok {
+		b = newBreaker(host, cfg)
+		s.breakers[key] = b
+	}
+	return b
+}
+
+// ServeHTTP 以 json 输出所有熔断器的状态，挂在 /admin/breakers 上
+func (s *breakerSet) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	statuses := make([]breakerStatus, 0, len(s.breakers))
+	for _, b := range s.breakers {
+		statuses = append(statuses, b.status())
+	}
+	s.mu.Unlock()
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Host != statuses[j].Host {
+			return statuses[i].Host < statuses[j].Host
+		}
+		return statuses[i].FailureThreshold < statuses[j].FailureThreshold
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
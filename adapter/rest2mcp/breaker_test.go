@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestBreakerStates(t *testing.T) {
+	now := time.Now()
+	b := newBreaker("svc", BreakerConfig{FailureThreshold: 2, OpenTimeout: Duration(10 * time.Second), HalfOpenRequests: 2})
+	b.now = func() time.Time { return now }
+	call := func(ok bool) {
+		ticket, err := b.allow()
+		if err != nil {
+			t.Fatalf("expected breaker to allow: %v", err)
+		}
+		b.record(ticket, ok)
+	}
+
+	// 成功会清零连续失败次数
+	for _, ok := range []bool{false, true, false} {
+		call(ok)
+	}
+	if b.state != breakerClosed {
+		t.Fatalf("expected closed, got %s", b.state)
+	}
+	// 关闭时放行的慢请求
+	slow, _ := b.allow()
+	call(false)
+	if b.state != breakerOpen {
+		t.Fatalf("expected open after 2 consecutive failures, got %s", b.state)
+	}
+	if _, err := b.allow(); err == nil {
+		t.Fatal("expected open breaker to reject")
+	}
+
+	// 超过 openTimeout 后半开，只放行 halfOpenRequests 个请求
+	now = now.Add(11 * time.Second)
+	probe1, err := b.allow()
+	if err != nil {
+		t.Fatalf("expected half-open breaker to allow: %v", err)
+	}
+	probe2, err := b.allow()
+	if err != nil {
+		t.Fatalf("expected half-open breaker to allow second probe: %v", err)
+	}
+	if _, err := b.allow(); err == nil {
+		t.Fatal("expected half-open breaker to reject extra requests")
+	}
+	// 慢请求在半开时才结束，既不占用也不释放试探名额，也不算试探成功
+	b.record(slow, true)
+	b.record(probe1, true)
+	if b.state != breakerHalfOpen || b.inFlight != 1 {
+		t.Fatalf("expected half-open until all probes succeed, got %s with %d in flight", b.state, b.inFlight)
+	}
+	b.record(probe2, true)
+	if b.state != breakerClosed {
+		t.Fatalf("expected closed after probes succeed, got %s", b.state)
+	}
+
+	// 半开时试探失败重新熔断
+	call(false)
+	call(false)
+	now = now.Add(11 * time.Second)
+	call(false)
+	if b.state != breakerOpen {
+		t.Fatalf("expected open after failed probe, got %s", b.state)
+	}
+
+	// 取消的试探释放名额
+	now = now.Add(11 * time.Second)
+	probe1, _ = b.allow()
+	probe2, _ = b.allow()
+	b.abandon(probe1)
+	if _, err := b.allow(); err != nil {
+		t.Fatalf("expected abandoned probe to free its slot: %v", err)
+	}
+	b.record(probe2, true)
+}
+
+func TestBreakerSetConfigs(t *testing.T) {
+	// 同一个 host 上策略不同的上游使用各自的熔断器
+	s := newBreakerSet()
+	strict := BreakerConfig{FailureThreshold: 1}
+	lenient := BreakerConfig{FailureThreshold: 5}
+	if s.get("svc", strict) == s.get("svc", lenient) {
+		t.Fatal("expected separate breakers for different configs")
+	}
+	if s.get("svc", strict) != s.get("svc", strict) {
+		t.Fatal("expected the same breaker for the same host and config")
+	}
+	ticket, _ := s.get("svc", strict).allow()
+	s.get("svc", strict).record(ticket, false)
+	if s.get("svc", strict).status().State != breakerOpen || s.get("svc", lenient).status().State != breakerClosed {
+		t.Errorf("expected only the strict breaker to open")
+	}
+}
+
+func TestBreakerFailFast(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	cfg := &Config{
+		Server:    ServerConfig{Breaker: BreakerConfig{FailureThreshold: 2, OpenTimeout: Duration(time.Minute)}},
+		Upstreams: map[string]UpstreamConfig{"svc": {BaseURL: upstream.URL}},
+		Tools:     []ToolConfig{{Name: "ping", RequestTemplate: RequestTemplate{Upstream: "svc", URL: "/ping"}}},
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	r := a.routes[0]
+
+	for i := 0; i < 2; i++ {
+		result, err := r.handle(context.Background(), mcp.CallToolRequest{})
+		if err != nil || !result.IsError {
+			t.Fatalf("expected upstream error result, got %v", err)
+		}
+	}
+	result, err := r.handle(context.Background(), mcp.CallToolRequest{})
+	if err != nil {
+		t.Fatalf("expected tool error while breaker is open, got protocol error: %v", err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "circuit breaker open") {
+		t.Errorf("unexpected result while breaker is open: %+v", result)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected open breaker to skip upstream, got %d calls", n)
+	}
+
+	// 管理接口
+	rec := httptest.NewRecorder()
+	a.adminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/breakers", nil))
+	var statuses []breakerStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("failed to decode admin response: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].State != breakerOpen || statuses[0].OpenedAt == nil {
+		t.Errorf("unexpected breaker status %s", rec.Body.String())
+	}
+
+	// openTimeout 之后试探成功，恢复正常
+	healthy.Store(true)
+	b := a.breakers.get(strings.TrimPrefix(upstream.URL, "http://"), cfg.Server.Breaker)
+	b.openedAt = b.openedAt.Add(-2 * time.Minute)
+	if result, err := r.handle(context.Background(), mcp.CallToolRequest{}); err != nil || result.IsError {
+		t.Fatalf("expected probe to succeed, got %v %+v", err, result)
+	}
+	if b.status().State != breakerClosed {
+		t.Errorf("expected breaker to close, got %s", b.status().State)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheConfig 为只读的 tool 开启内存中的响应缓存，只缓存 GET、HEAD 请求的 200 响应
+type CacheConfig struct {
+	// TTL 是缓存的最长有效期；上游的 Cache-Control: max-age 更短时以它为准，no-cache 时每次都向上游验证，no-store 时不缓存。
+	// 不配置时完全按 max-age 缓存，响应没有 max-age 时每次都向上游验证
+	TTL Duration `json:"ttl"`
+	// MaxEntries 是缓存的最大条数，超过后淘汰最久未使用的，默认 256
+	MaxEntries int `json:"maxEntries"`
+}
+
+type responseCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	stats   cacheStats
+}
+
+type cacheEntry struct {
+	key     string
+	resp    *upstreamResponse
+	expires time.Time
+}
+
+// cacheStats 是缓存的命中情况，Revalidated 是过期后经上游确认未变化（304）的次数
+type cacheStats struct {
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Revalidated int64 `json:"revalidated"`
+}
+
+func newResponseCache(cfg *CacheConfig) *responseCache {
+	if cfg == nil {
+		return nil
+	}
+	c := &responseCache{
+		ttl:        time.Duration(cfg.TTL),
+		maxEntries: cfg.MaxEntries,
+		now:        time.Now,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = 256
+	}
+	return c
+}
+
+// cacheKey 由方法、路径、query 和所有请求头（包括认证信息）计算，不同凭证的请求不会共用缓存。
+// 使用上游时不包含实例地址，负载均衡到不同实例的相同请求共用缓存
+func (r *route) cacheKey(req *http.Request) string {
+	h := sha256.New()
+	target := req.URL.String()
+	if r.upstream != nil {
+		target = r.upstream.name + " " + req.URL.RequestURI()
+	}
+	h.Write([]byte(req.Method + " " + target + "\n"))
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h.Write([]byte(name + ": " + strings.Join(req.Header[name], ",") + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lookup 返回缓存的响应以及它是否仍然新鲜
+func (c *responseCache) lookup(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	entry := el.Value.(*cacheEntry)
+	if c.now().Before(entry.expires) {
+		c.stats.Hits++
+		return entry, true
+	}
+	return entry, false
+}
+
+// conditional 为过期的缓存加上 If-None-Match、If-Modified-Since，让上游可以返回 304
+func (e *cacheEntry) conditional(req *http.Request) bool {
+	etag, modified := e.resp.header.Get("ETag"), e.resp.header.Get("Last-Modified")
+	if etag != "" && req.Header.Get("If-None-Match") == "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if modified != "" && req.Header.Get("If-Modified-Since") == "" {
+		req.Header.Set("If-Modified-Since", modified)
+	}
+	return etag != "" || modified != ""
+}
+
+// update 处理上游的响应：304 时刷新并返回缓存的响应，200 时按 Cache-Control 存入缓存
+func (c *responseCache) update(key string, stale *cacheEntry, resp *upstreamResponse) *upstreamResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if resp.status == http.StatusNotModified && stale != nil {
+		c.stats.Revalidated++
+		stale.expires = c.now().Add(c.lifetime(resp.header))
+		return stale.resp
+	}
+	c.stats.Misses++
+	if resp.status != http.StatusOK || resp.streamed || noStore(resp.header) {
+		c.remove(key)
+		return resp
+	}
+	entry := &cacheEntry{key: key, resp: resp, expires: c.now().Add(c.lifetime(resp.header))}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+	} else {
+		c.entries[key] = c.lru.PushFront(entry)
+	}
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back().Value.(*cacheEntry).key)
+	}
+	return resp
+}
+
+func (c *responseCache) remove(key string) {
+	if el, ok := c.entries[key]; ok {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// lifetime 取 ttl 与 max-age 中较短的一个，no-cache 时为 0
+func (c *responseCache) lifetime(header http.Header) time.Duration {
+	ttl := c.ttl
+	for _, directive := range cacheDirectives(header) {
+		switch {
+		case directive == "no-cache":
+			return 0
+		case strings.HasPrefix(directive, "max-age="):
+			if secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+				// 没有配置 ttl 时以 max-age 为准
+				if age := time.Duration(secs) * time.Second; ttl <= 0 || age < ttl {
+					ttl = age
+				}
+			}
+		}
+	}
+	return max(ttl, 0)
+}
+
+func noStore(header http.Header) bool {
+	for _, directive := range cacheDirectives(header) {
+		if directive == "no-store" {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheDirectives(header http.Header) []string {
+	var directives []string
+	for _, v := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			directives = append(directives, strings.ToLower(strings.TrimSpace(d)))
+		}
+	}
+	return directives
+}
+
+// cacheStatus 是管理接口返回的缓存状态
+type cacheStatus struct {
+	Tool    string `json:"tool"`
+	Entries int    `json:"entries"`
+	cacheStats
+}
+
+func (c *responseCache) status(tool string) cacheStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return cacheStatus{Tool: tool, Entries: c.lru.Len(), cacheStats: c.stats}
+}
+
+// cacheHandler 以 json 输出开启了缓存的 tool 的命中情况，挂在 /admin/cache 上
+func (a *adapter) cacheHandler(w http.ResponseWriter, _ *http.Request) {
+	statuses := []cacheStatus{}
+	for _, r := range a.routes {
+		if r.cache != nil {
+			statuses = append(statuses, r.cache.status(r.Name))
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestResponseCache(t *testing.T) {
+	var calls, notModified atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/etag":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte("user " + r.URL.Query().Get("id")))
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+			w.Write([]byte("secret"))
+		case "/max-age":
+			w.Header().Set("Cache-Control", "max-age=1")
+			w.Write([]byte("short"))
+		}
+	}))
+	defer upstream.Close()
+
+	cfg, err := parseConfig([]byte(`
+upstreams:
+  svc:
+    baseURL: ` + upstream.URL + `
+tools:
+  - name: etag
+    args: [{name: id, position: query}]
+    requestTemplate: {upstream: svc, url: /etag}
+    cache: {ttl: 1m}
+  - name: no_store
+    requestTemplate: {upstream: svc, url: /no-store}
+    cache: {ttl: 1m}
+  - name: max_age
+    requestTemplate: {upstream: svc, url: /max-age}
+    cache: {ttl: 1m}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	now := time.Now()
+	for _, r := range a.routes {
+		r.cache.now = func() time.Time { return now }
+	}
+	call := func(r *route, id string) string {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{"id": id}
+		result, err := r.handle(context.Background(), request)
+		if err != nil || result.IsError {
+			t.Fatalf("%s: unexpected result %v %+v", r.Name, err, result)
+		}
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	// 相同的请求命中缓存，不同的参数分别缓存
+	etag := a.routes[0]
+	for _, id := range []string{"1", "1", "2", "1"} {
+		if text := call(etag, id); text != "user "+id {
+			t.Errorf("got %q for id %s", text, id)
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected 2 upstream calls, got %d", n)
+	}
+	// 过期后带 If-None-Match 验证，304 时返回缓存的内容
+	now = now.Add(2 * time.Minute)
+	if text := call(etag, "1"); text != "user 1" || notModified.Load() != 1 {
+		t.Errorf("expected revalidated response, got %q with %d 304s", text, notModified.Load())
+	}
+	if text := call(etag, "1"); text != "user 1" || calls.Load() != 3 {
+		t.Errorf("expected refreshed entry to be fresh, got %q with %d calls", text, calls.Load())
+	}
+
+	// no-store 不缓存，max-age 比 ttl 短时以 max-age 为准
+	calls.Store(0)
+	call(a.routes[1], "")
+	call(a.routes[1], "")
+	call(a.routes[2], "")
+	call(a.routes[2], "")
+	now = now.Add(2 * time.Second)
+	call(a.routes[2], "")
+	if n := calls.Load(); n != 4 {
+		t.Errorf("expected 4 upstream calls, got %d", n)
+	}
+
+	// 管理接口输出命中情况
+	rec := httptest.NewRecorder()
+	a.adminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/cache", nil))
+	var statuses []cacheStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("failed to decode admin response: %v", err)
+	}
+	want := []cacheStatus{
+		{Tool: "etag", Entries: 2, cacheStats: cacheStats{Hits: 3, Misses: 2, Revalidated: 1}},
+		{Tool: "no_store", Entries: 0, cacheStats: cacheStats{Hits: 0, Misses: 2}},
+		{Tool: "max_age", Entries: 1, cacheStats: cacheStats{Hits: 1, Misses: 2}},
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("unexpected cache status %s", rec.Body.String())
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Errorf("got %+v, want %+v", statuses[i], want[i])
+		}
+	}
+
+	// 只有 GET、HEAD 请求可以缓存
+	if _, err := parseConfig([]byte(`tools: [{name: a, requestTemplate: {url: http://localhost, method: POST}, cache: {ttl: 1m}}]`)); err == nil {
+		t.Error("expected error for cached POST tool")
+	}
+}
+
+func TestResponseCacheWithoutTTL(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/max-age" {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	// 没有配置 ttl 时按上游的 max-age 缓存，没有 max-age 时不缓存
+	cfg, err := parseConfig([]byte(`
+tools:
+  - name: max_age
+    requestTemplate: {url: ` + upstream.URL + `/max-age}
+    cache: {}
+  - name: plain
+    requestTemplate: {url: ` + upstream.URL + `/plain}
+    cache: {}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	now := time.Now()
+	for _, r := range a.routes {
+		r.cache.now = func() time.Time { return now }
+	}
+	call := func(r *route) {
+		if result, err := r.handle(context.Background(), mcp.CallToolRequest{}); err != nil || result.IsError {
+			t.Fatalf("%s: unexpected result %v %+v", r.Name, err, result)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		call(a.routes[0])
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected max-age response to be cached, got %d upstream calls", n)
+	}
+	now = now.Add(61 * time.Second)
+	call(a.routes[0])
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected entry to expire after max-age, got %d upstream calls", n)
+	}
+
+	calls.Store(0)
+	call(a.routes[1])
+	call(a.routes[1])
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected response without max-age not to be cached, got %d upstream calls", n)
+	}
+}
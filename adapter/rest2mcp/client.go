@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ClientConfig 是所有上游共用的 http client 配置
+type ClientConfig struct {
+	// Timeout 是 tool 没有单独配置 timeout 时，一次调用（含重试）的超时时间，默认 30s
+	Timeout             Duration `json:"timeout"`
+	MaxIdleConnsPerHost int      `json:"maxIdleConnsPerHost"`
+	IdleConnTimeout     Duration `json:"idleConnTimeout"`
+}
+
+// RetryConfig 描述 tool 调用失败后的重试策略，退避时间按指数增长并加入随机抖动
+type RetryConfig struct {
+	// Attempts 是包括第一次在内的最大尝试次数，小于等于 1 时不重试
+	Attempts int `json:"attempts"`
+	// Backoff 是第一次重试前的等待时间，默认 100ms，之后每次翻倍
+	Backoff    Duration `json:"backoff"`
+	MaxBackoff Duration `json:"maxBackoff"`
+	// StatusCodes 是需要重试的响应状态码，默认 502、503、504
+	StatusCodes []int `json:"statusCodes"`
+	// NonIdempotent 为 true 时 POST、PATCH 也会重试，默认只重试幂等的方法
+	NonIdempotent bool `json:"nonIdempotent"`
+}
+
+const defaultTimeout = 30 * time.Second
+
+var defaultRetryStatusCodes = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+func newHTTPClient(cfg ClientConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = time.Duration(cfg.IdleConnTimeout)
+	}
+	// 超时由每次调用的 context 控制，client 本身不设置 Timeout
+	return &http.Client{Transport: transport}
+}
+
+// upstreamResponse 是已经读完响应体的上游响应
+type upstreamResponse struct {
+	status int
+	header http.Header
+	body   []byte
+	// url 是请求的地址，不含认证信息
+	url string
+	// streamed 为 true 时 body 是流式响应聚合后的文本
+	streamed bool
+}
+
+// send 发送请求并按重试策略重试。调用方的 ctx 被取消（客户端取消、会话关闭）时立即中止上游请求
+func (r *route) send(ctx context.Context, args map[string]any) (*upstreamResponse, error) {
+	attempts := max(r.Retry.Attempts, 1)
+	for attempt := 1; ; attempt++ {
+		resp, err := r.sendOnce(ctx, args)
+		if attempt >= attempts || !r.shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+
+		wait := r.backoff(attempt)
+		if resp != nil {
+			// 上游给出了 Retry-After 时以它为准，但不超过 maxBackoff
+			if secs, err := strconv.Atoi(resp.header.Get("Retry-After")); err == nil {
+				wait = min(time.Duration(secs)*time.Second, r.maxBackoff())
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				return resp, nil
+			}
+			return nil, err
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (r *route) sendOnce(ctx context.Context, args map[string]any) (*upstreamResponse, error) {
+	var inst *instance
+	if r.upstream != nil {
+		inst = r.upstream.pick(args)
+	}
+	req, err := r.buildRequest(ctx, args, inst)
+	if err != nil {
+		return nil, err
+	}
+	// 在注入认证信息之前记录地址，避免 query 中的凭证出现在结果里
+	rawURL := req.URL.String()
+	if err := r.authenticate(ctx, req); err != nil {
+		return nil, err
+	}
+
+	// 缓存新鲜时不请求上游，过期时带上 ETag、Last-Modified 向上游验证
+	var cacheKey string
+	var stale *cacheEntry
+	if r.cache != nil && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+		cacheKey = r.cacheKey(req)
+		entry, fresh := r.cache.lookup(cacheKey)
+		if fresh {
+			return entry.resp, nil
+		}
+		if entry != nil && entry.conditional(req) {
+			stale = entry
+		}
+	}
+
+	// 超出 tool 或上游的限流配置时排队或直接失败
+	release, err := r.limit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// 上游熔断时直接失败，不再发出请求
+	b := r.breakers.get(req.URL.Host, r.breakerConfig)
+	var ticket breakerTicket
+	if b != nil {
+		if ticket, err = b.allow(); err != nil {
+			return nil, err
+		}
+	}
+
+	if inst != nil {
+		inst.inFlight.Add(1)
+	}
+	resp, err := r.client.Do(req)
+	var body []byte
+	streamed := false
+	if err == nil {
+		// 流式响应边读边转发给客户端，body 为聚合后的结果
+		if format := r.stream.formatOf(resp); format != "" && resp.StatusCode/100 == 2 {
+			body, err = r.stream.read(ctx, format, resp.Body)
+			streamed = true
+		} else {
+			body, err = io.ReadAll(resp.Body)
+		}
+		resp.Body.Close()
+	}
+	if inst != nil {
+		inst.inFlight.Add(-1)
+	}
+	if b != nil {
+		switch {
+		case ctx.Err() != nil:
+			b.abandon(ticket)
+		default:
+			b.record(ticket, err == nil && resp.StatusCode < 500)
+		}
+	}
+	if err != nil {
+		// 已经转发过块的流不再重试
+		var interrupted *streamInterruptedError
+		callErr := fmt.Errorf("failed to call %s %s: %v", req.Method, req.URL.Path, err)
+		if errors.As(err, &interrupted) {
+			return nil, callErr
+		}
+		return nil, &transportError{callErr}
+	}
+	if resp.StatusCode == http.StatusUnauthorized && r.upstream != nil {
+		if a, ok := r.upstream.auth.(interface{ invalidate() }); ok {
+			a.invalidate()
+		}
+	}
+	result := &upstreamResponse{status: resp.StatusCode, header: resp.Header, body: body, url: rawURL, streamed: streamed}
+	if cacheKey != "" {
+		result = r.cache.update(cacheKey, stale, result)
+	}
+	return result, nil
+}
+
+// authenticate 注入上游认证信息，凭证不经过模型
+func (r *route) authenticate(ctx context.Context, req *http.Request) error {
+	if r.upstream == nil {
+		return nil
+	}
+	// 会话提供了自己的凭证时代替上游配置的认证信息
+	if sa := r.upstream.sessionAuth; sa != nil {
+		if credential, ok := r.sessions.get(sessionIDFrom(ctx), r.upstream.name); ok {
+			sa.apply(req, credential)
+			return nil
+		}
+		if sa.Required {
+			return &missingCredentialsError{upstream: r.upstream.name, header: sa.Header}
+		}
+	}
+	if r.upstream.auth == nil {
+		return nil
+	}
+	if err := r.upstream.auth.apply(ctx, req); err != nil {
+		return fmt.Errorf("failed to authenticate to upstream %s: %v", r.upstream.name, err)
+	}
+	return nil
+}
+
+// transportError 表示请求没有拿到上游响应，只有这类错误才会重试
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return e.err.Error() }
+
+func (r *route) shouldRetry(ctx context.Context, resp *upstreamResponse, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	// GraphQL query 虽然使用 POST，但和 GET 一样可以安全重试
+	idempotent := isIdempotent(r.RequestTemplate.Method) || r.graphql != nil && r.graphql.kind == "query"
+	if !r.Retry.NonIdempotent && !idempotent {
+		return false
+	}
+	if err != nil {
+		var te *transportError
+		return errors.As(err, &te)
+	}
+	codes := r.Retry.StatusCodes
+	if len(codes) == 0 {
+		codes = defaultRetryStatusCodes
+	}
+	return slices.Contains(codes, resp.status)
+}
+
+// backoff 返回第 attempt 次失败后的等待时间：指数退避，并在 [d/2, d] 之间随机抖动
+func (r *route) backoff(attempt int) time.Duration {
+	d := time.Duration(r.Retry.Backoff)
+	if d <= 0 {
+		d = 100 * time.Millisecond
+	}
+	for i := 1; i < attempt && d < r.maxBackoff(); i++ {
+		d *= 2
+	}
+	d = min(d, r.maxBackoff())
+	return d/2 + rand.N(d/2+1)
+}
+
+func (r *route) maxBackoff() time.Duration {
+	if r.Retry.MaxBackoff > 0 {
+		return time.Duration(r.Retry.MaxBackoff)
+	}
+	return 2 * time.Second
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRetry(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		switch {
+		case r.URL.Path == "/drop" && n == 1:
+			// 第一次直接断开连接，模拟传输错误
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		case r.URL.Path == "/flaky" && n < 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/bad":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer upstream.Close()
+
+	retry := RetryConfig{Attempts: 3, Backoff: Duration(time.Millisecond)}
+	tests := []struct {
+		name      string
+		path      string
+		method    string
+		retry     RetryConfig
+		wantCalls int32
+		wantError bool
+	}{
+		{name: "retry status code", path: "/flaky", method: "GET", retry: retry, wantCalls: 3},
+		{name: "retry transport error", path: "/drop", method: "GET", retry: retry, wantCalls: 2},
+		{name: "no retry by default", path: "/flaky", method: "GET", wantCalls: 1, wantError: true},
+		{name: "no retry for post", path: "/flaky", method: "POST", retry: retry, wantCalls: 1, wantError: true},
+		{name: "retry non idempotent", path: "/flaky", method: "POST", retry: RetryConfig{Attempts: 3, Backoff: Duration(time.Millisecond), NonIdempotent: true}, wantCalls: 3},
+		{name: "status not retried", path: "/bad", method: "GET", retry: retry, wantCalls: 1, wantError: true},
+		{name: "attempts exhausted", path: "/flaky", method: "GET", retry: RetryConfig{Attempts: 2, Backoff: Duration(time.Millisecond)}, wantCalls: 2, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			r, err := newRoute(ToolConfig{
+				Name:            "flaky",
+				RequestTemplate: RequestTemplate{URL: upstream.URL + tt.path, Method: tt.method},
+				Retry:           tt.retry,
+			}, nil)
+			if err != nil {
+				t.Fatalf("failed to create route: %v", err)
+			}
+			result, err := r.handle(context.Background(), mcp.CallToolRequest{})
+			if err != nil {
+				t.Fatalf("unexpected protocol error: %v", err)
+			}
+			if result.IsError != tt.wantError {
+				t.Errorf("expected IsError %v, got %+v", tt.wantError, result)
+			}
+			if n := calls.Load(); n != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, n)
+			}
+		})
+	}
+}
+
+func TestTimeoutAndCancel(t *testing.T) {
+	aborted := make(chan struct{}, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			aborted <- struct{}{}
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer upstream.Close()
+
+	r, err := newRoute(ToolConfig{
+		Name:            "slow",
+		RequestTemplate: RequestTemplate{URL: upstream.URL},
+		Timeout:         Duration(100 * time.Millisecond),
+		Retry:           RetryConfig{Attempts: 5, Backoff: Duration(time.Millisecond)},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create route: %v", err)
+	}
+
+	// 超过 tool 的超时时间后中止，且不会继续重试
+	start := time.Now()
+	if _, err := r.handle(context.Background(), mcp.CallToolRequest{}); err == nil {
+		t.Error("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected call to abort after timeout, took %v", elapsed)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Error("expected upstream request to be aborted")
+	}
+
+	// 客户端取消时中止上游请求
+	r.timeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := r.handle(ctx, mcp.CallToolRequest{}); err == nil {
+		t.Error("expected cancellation error")
+	}
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Error("expected upstream request to be aborted after cancellation")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"mcp-demo/internal/config"
+)
+
+// Config 是 adapter 的配置文件，结构参考 higress 的 mcp server 配置：
+// 每个 tool 声明入参、如何构造 http 请求以及如何把响应转换成 tool 结果
+type Config struct {
+	Server ServerConfig `json:"server"`
+	// Upstreams 是按名称引用的上游服务，tool 通过 requestTemplate.upstream 引用
+	Upstreams map[string]UpstreamConfig `json:"upstreams"`
+	Tools     []ToolConfig              `json:"tools"`
+	// Resources 把只读的 GET 接口暴露成 mcp resource
+	Resources []ResourceConfig `json:"resources"`
+}
+
+type ServerConfig struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Addr 是 sse 服务监听的地址，如 :8090
+	Addr string `json:"addr"`
+	// AdminAddr 是管理接口（/admin/breakers、/admin/upstreams、/admin/cache）单独监听的地址，如 127.0.0.1:8094，
+	// 为空时不提供管理接口。管理接口没有认证，不要监听在公网地址上
+	AdminAddr string       `json:"adminAddr"`
+	Client    ClientConfig `json:"client"`
+	// MaxResponseSize 是返回给模型的响应体的默认上限（字节）
+	MaxResponseSize int `json:"maxResponseSize"`
+	// Breaker 是默认的熔断策略，按上游 host 和策略分别统计
+	Breaker BreakerConfig `json:"breaker"`
+	// DryRun 为 true 时所有 tool 只返回构造好的 http 请求，不发送，用于预发环境排查参数映射，见 explain.go
+	DryRun bool `json:"dryRun"`
+	// Explain 为 true 时为每个 tool 注册一个 <tool>__explain，返回这次调用会发送的请求
+	Explain bool `json:"explain"`
+}
+
+// loadConfig 读取 yaml 或 json 格式的配置文件
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	return parseConfig(data)
+}
+
+func parseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	if err := config.Decode(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %v", err)
+	}
+	if cfg.Server.Name == "" {
+		cfg.Server.Name = "rest2mcp adapter"
+	}
+	if cfg.Server.Version == "" {
+		cfg.Server.Version = "1.0.0"
+	}
+	if cfg.Server.Addr == "" {
+		cfg.Server.Addr = ":8090"
+	}
+	// 构造一次 adapter，提前发现模板、上游配置中的错误
+	if _, err := newAdapter(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Duration 是配置中的时长，可以写成 "30s"、"1m"，数字按秒处理
+type Duration = config.Duration
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := loadConfig("adapter.yaml")
+	if err != nil {
+		t.Fatalf("failed to load adapter.yaml: %v", err)
+	}
+	if cfg.Server.Addr != ":8090" || len(cfg.Tools) != 1 || cfg.Tools[0].Name != "hello_world" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	// json 格式，未配置的 server 字段使用默认值
+	cfg, err = parseConfig([]byte(`{"tools":[{"name":"a","requestTemplate":{"url":"http://localhost/a"}}]}`))
+	if err != nil {
+		t.Fatalf("failed to parse json config: %v", err)
+	}
+	if cfg.Server.Name == "" || cfg.Server.Addr != ":8090" {
+		t.Errorf("expected default server config, got %+v", cfg.Server)
+	}
+
+	invalid := map[string]string{
+		"missing name":     `tools: [{requestTemplate: {url: http://localhost}}]`,
+		"duplicate name":   `tools: [{name: a, requestTemplate: {url: http://localhost}}, {name: a, requestTemplate: {url: http://localhost}}]`,
+		"missing url":      `tools: [{name: a}]`,
+		"invalid template": `tools: [{name: a, requestTemplate: {url: "http://localhost/{{.args.id"}}]`,
+	}
+	for name, data := range invalid {
+		if _, err := parseConfig([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestConfigToolHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"user": map[string]string{
+				"path":   r.URL.Path,
+				"source": r.Header.Get("X-Source"),
+				"body":   string(body),
+			},
+			"noise": strings.Repeat("x", 100),
+		})
+	}))
+	defer upstream.Close()
+
+	cfg, err := parseConfig([]byte(`
+tools:
+  - name: create_user
+    args:
+      - name: name
+        type: string
+        required: true
+      - name: group
+        type: string
+        required: true
+    requestTemplate:
+      url: ` + upstream.URL + `/groups/{{.args.group}}/users
+      method: POST
+      headers:
+        - key: X-Source
+          value: mcp-{{.args.group}}
+      body: '{"user": {"name": "{{.args.name}}"}}'
+    responseTemplate:
+      body: "{{.user.path}} {{.user.source}} {{.user.body}}"
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	r, err := newRoute(cfg.Tools[0], nil)
+	if err != nil {
+		t.Fatalf("failed to create route: %v", err)
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"name": "Alice", "group": "admin"}
+	result, err := r.handle(context.Background(), request)
+	if err != nil {
+		t.Fatalf("failed to call tool: %v", err)
+	}
+	expected := `/groups/admin/users mcp-admin {"user": {"name": "Alice"}}`
+	if actual := result.Content[0].(mcp.TextContent).Text; actual != expected {
+		t.Errorf("unexpected result: got %q, want %q", actual, expected)
+	}
+}
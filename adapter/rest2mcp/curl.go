@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// curlFlags 是不影响请求内容、可以忽略的 curl 开关
+var curlFlags = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true, "-k": true, "--insecure": true,
+	"-L": true, "--location": true, "-v": true, "--verbose": true, "-i": true, "--include": true,
+	"-f": true, "--fail": true, "--compressed": true, "-g": true, "--globoff": true,
+}
+
+// curlIgnoredOptions 是带参数但不影响请求内容、可以忽略的 curl 选项
+var curlIgnoredOptions = map[string]bool{
+	"-o": true, "--output": true, "-m": true, "--max-time": true, "--connect-timeout": true,
+	"--retry": true, "-w": true, "--write-out": true,
+}
+
+// parseCurl 解析一条 curl 命令，生成 ToolConfig。支持 -X、-H、-d（及 --data-raw 等）、--data-urlencode、-u、-G 和 url，
+// 其中 {{name}} 或 {{name:integer}} 形式的变量变成 tool 的入参。name 为空时用 method + 路径生成名称
+func parseCurl(command, name, baseURL, upstream string) (ToolConfig, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return ToolConfig{}, err
+	}
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	var (
+		method, rawURL string
+		headers        [][2]string
+		data           []string
+		urlencoded     [][2]string
+		user           string
+		get            bool
+	)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if rawURL != "" {
+				return ToolConfig{}, fmt.Errorf("curl: only one url is supported, got %q and %q", rawURL, arg)
+			}
+			rawURL = arg
+			continue
+		}
+		option, value, hasValue := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			option, value, hasValue = strings.Cut(arg, "=")
+		} else if len(arg) > 2 && !curlCombinedFlags(arg) {
+			// -XPOST、-HAccept:... 这样紧跟着值的短选项
+			option, value, hasValue = arg[:2], arg[2:], true
+		}
+		if curlFlags[option] || curlCombinedFlags(option) {
+			continue
+		}
+		if option == "-G" || option == "--get" {
+			get = true
+			continue
+		}
+		if option == "-I" || option == "--head" {
+			method = http.MethodHead
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return ToolConfig{}, fmt.Errorf("curl: option %s requires a value", option)
+			}
+			i++
+			value = args[i]
+		}
+		switch option {
+		case "-X", "--request":
+			method = strings.ToUpper(value)
+		case "-H", "--header":
+			key, v, ok := strings.Cut(value, ":")
+			if !ok {
+				return ToolConfig{}, fmt.Errorf("curl: invalid header %q", value)
+			}
+			headers = append(headers, [2]string{strings.TrimSpace(key), strings.TrimSpace(v)})
+		case "-A", "--user-agent":
+			headers = append(headers, [2]string{"User-Agent", value})
+		case "-e", "--referer":
+			headers = append(headers, [2]string{"Referer", value})
+		case "-b", "--cookie":
+			headers = append(headers, [2]string{"Cookie", value})
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			if strings.HasPrefix(value, "@") && option != "--data-raw" {
+				return ToolConfig{}, fmt.Errorf("curl: reading data from a file (%s) is not supported", value)
+			}
+			data = append(data, value)
+		case "--json":
+			data = append(data, value)
+			headers = append(headers, [2]string{"Content-Type", "application/json"}, [2]string{"Accept", "application/json"})
+		case "--data-urlencode":
+			pair, err := curlURLEncoded(value)
+			if err != nil {
+				return ToolConfig{}, err
+			}
+			urlencoded = append(urlencoded, pair)
+		case "-u", "--user":
+			user = value
+		case "--url":
+			rawURL = value
+		default:
+			if !curlIgnoredOptions[option] {
+				return ToolConfig{}, fmt.Errorf("curl: unsupported option %s", option)
+			}
+		}
+	}
+	if rawURL == "" {
+		return ToolConfig{}, fmt.Errorf("curl: url is required")
+	}
+
+	switch {
+	case method != "":
+	case get:
+		method = http.MethodGet
+	case len(data) > 0 || len(urlencoded) > 0:
+		method = http.MethodPost
+	default:
+		method = http.MethodGet
+	}
+	path, rawQuery, _ := strings.Cut(rawURL, "?")
+	if scheme, rest, ok := strings.Cut(path, "://"); ok && scheme != "" {
+		_, path, _ = strings.Cut(rest, "/")
+		path = "/" + path
+	}
+	if name == "" {
+		name = operationName(method, placeholderPattern.ReplaceAllString(path, "$1"))
+	}
+	b := newToolBuilder(name, method+" "+placeholderPattern.ReplaceAllString(rawURL, "{$1}"), method, nil)
+	b.declareTypes(command)
+	if err := b.setURL(rawURL, baseURL, upstream); err != nil {
+		return ToolConfig{}, err
+	}
+
+	query := splitQuery(rawQuery)
+	body := strings.Join(data, "&")
+	// -G 把数据放到 query 中
+	if get {
+		query = append(query, splitQuery(body)...)
+		query = append(query, urlencoded...)
+		body, urlencoded = "", nil
+	}
+	for _, q := range query {
+		b.param(positionQuery, q[0], q[1])
+	}
+
+	contentType := ""
+	for _, h := range headers {
+		if strings.EqualFold(h[0], "Content-Type") {
+			contentType = h[1]
+		}
+		b.param(positionHeader, h[0], h[1])
+	}
+	if user != "" {
+		if placeholderPattern.MatchString(user) {
+			return ToolConfig{}, fmt.Errorf("curl: variables in -u are not supported, configure basic auth on the upstream instead")
+		}
+		log.Printf("tool %s: -u is imported as a fixed Authorization header, consider configuring basic auth on the upstream", b.tool.Name)
+		b.param(positionHeader, "Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user)))
+	}
+
+	trimmed := strings.TrimSpace(body)
+	switch {
+	case body == "" && len(urlencoded) == 0:
+	case len(urlencoded) == 0 && (strings.Contains(contentType, "json") || strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")):
+		b.jsonBody(body)
+	case contentType == "" || strings.Contains(contentType, "x-www-form-urlencoded"):
+		b.formBody(append(splitQuery(body), urlencoded...))
+	default:
+		b.tool.RequestTemplate.Body = b.template(body, "", true)
+	}
+	return b.tool, nil
+}
+
+// curlCombinedFlags 判断 -sSL 这样合在一起的开关
+func curlCombinedFlags(arg string) bool {
+	if strings.HasPrefix(arg, "--") || len(arg) < 2 {
+		return false
+	}
+	for _, c := range arg[1:] {
+		if !curlFlags["-"+string(c)] {
+			return false
+		}
+	}
+	return true
+}
+
+// curlURLEncoded 解析 --data-urlencode 的参数：content、=content、name=content，不支持从文件读取
+func curlURLEncoded(value string) ([2]string, error) {
+	if i := strings.IndexAny(value, "=@"); i >= 0 && value[i] == '@' {
+		return [2]string{}, fmt.Errorf("curl: reading data from a file (%s) is not supported", value)
+	}
+	name, content, ok := strings.Cut(value, "=")
+	if !ok {
+		return [2]string{"", value}, nil
+	}
+	return [2]string{name, content}, nil
+}
+
+// splitCommand 按 shell 的规则拆分命令行，支持单引号、双引号、反斜杠转义和续行
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(c)
+			}
+		case quote == '"':
+			switch {
+			case c == '"':
+				quote = 0
+			case c == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]):
+				i++
+				if runes[i] != '\n' {
+					cur.WriteRune(runes[i])
+				}
+			default:
+				cur.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote, inArg = c, true
+		case c == '\\' && i+1 < len(runes):
+			i++
+			if runes[i] != '\n' && runes[i] != '\r' {
+				cur.WriteRune(runes[i])
+				inArg = true
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("curl: unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// curlCommand 实现 curl 子命令：把 curl 命令转换成 tool 配置，以 yaml 输出到 out
+//
+//	go run . curl -name get_user 'curl -H "Accept: application/json" https://api.example.com/users/{{id:integer}}'
+func curlCommand(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("curl", flag.ContinueOnError)
+	name := fs.String("name", "", "tool 名称，默认用 method + 路径生成")
+	description := fs.String("description", "", "tool 描述")
+	baseURL := fs.String("base-url", "", "替换 curl 命令中的地址")
+	upstream := fs.String("upstream", "", "使用配置文件中的哪个上游，此时 url 为相对路径")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: curl [-name name] [-description text] [-base-url url] [-upstream name] 'curl ...'")
+	}
+	// 整条命令作为一个参数传入，或者直接写在 -- 之后
+	command := fs.Arg(0)
+	if fs.NArg() > 1 {
+		quoted := make([]string, fs.NArg())
+		for i, a := range fs.Args() {
+			quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+		command = strings.Join(quoted, " ")
+	}
+	tool, err := parseCurl(command, *name, *baseURL, *upstream)
+	if err != nil {
+		return err
+	}
+	if *description != "" {
+		tool.Description = *description
+	}
+	var doc map[string]any
+	if err := remarshal(map[string]any{"tools": []ToolConfig{tool}}, &doc); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(pruneEmpty(doc)); err != nil {
+		return fmt.Errorf("failed to encode tool: %v", err)
+	}
+	return enc.Close()
+}
+
+// pruneEmpty 去掉配置中的空值和默认值，输出的配置只包含需要填写的字段
+func pruneEmpty(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := map[string]any{}
+		for k, item := range val {
+			if item = pruneEmpty(item); item != nil {
+				out[k] = item
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case []any:
+		var out []any
+		for _, item := range val {
+			if item = pruneEmpty(item); item != nil {
+				out = append(out, item)
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case string:
+		if val == "" || val == "0s" {
+			return nil
+		}
+	case bool:
+		if !val {
+			return nil
+		}
+	case float64:
+		if val == 0 {
+			return nil
+		}
+	}
+	return v
+}
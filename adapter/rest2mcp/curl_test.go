@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{`curl -H 'A: b c' "x\"y" z\ w`, []string{"curl", "-H", "A: b c", `x"y`, "z w"}},
+		{"curl \\\n  -d '' \\\r\n  url", []string{"curl", "-d", "", "url"}},
+		{`curl 'it'\''s'`, []string{"curl", "it's"}},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.command)
+		if err != nil {
+			t.Fatalf("failed to split %q: %v", tt.command, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("split %q: expected %q, got %q", tt.command, tt.want, got)
+		}
+	}
+	if _, err := splitCommand(`curl 'abc`); err == nil {
+		t.Errorf("expected error for unterminated quote")
+	}
+}
+
+func TestParseCurl(t *testing.T) {
+	tool, err := parseCurl(`curl -sSL -H 'Accept: application/json' 'https://api.example.com/v1/users/{{id:integer}}?verbose={{verbose:boolean}}&v=2'`, "", "", "")
+	if err != nil {
+		t.Fatalf("failed to parse curl: %v", err)
+	}
+	if tool.Name != "get_v1_users_id" || tool.RequestTemplate.Method != http.MethodGet || tool.RequestTemplate.URL != "https://api.example.com/v1/users/{id}" {
+		t.Errorf("unexpected tool %s %s %s", tool.Name, tool.RequestTemplate.Method, tool.RequestTemplate.URL)
+	}
+	wantArgs := []ArgConfig{
+		{Name: "id", Type: "integer", Position: positionPath, Required: true},
+		{Name: "verbose", Type: "boolean", Position: positionQuery},
+	}
+	if !reflect.DeepEqual(tool.Args, wantArgs) {
+		t.Errorf("expected args %+v, got %+v", wantArgs, tool.Args)
+	}
+
+	tool, err = parseCurl(`curl -XPUT https://api.example.com/users/{{id}} -u admin:secret --json '{"age": {{age:integer}}}'`, "update_user", "", "users")
+	if err != nil {
+		t.Fatalf("failed to parse curl: %v", err)
+	}
+	if tool.Name != "update_user" || tool.RequestTemplate.Method != http.MethodPut || tool.RequestTemplate.URL != "/users/{id}" || tool.RequestTemplate.Upstream != "users" {
+		t.Errorf("unexpected tool %s %s %s", tool.Name, tool.RequestTemplate.Method, tool.RequestTemplate.URL)
+	}
+	if tool.RequestTemplate.Body != `{"age": {{json .args.age}}}` || tool.arg("age").Type != "integer" {
+		t.Errorf("unexpected body %s", tool.RequestTemplate.Body)
+	}
+	if len(tool.RequestTemplate.Headers) != 3 || tool.RequestTemplate.Headers[2].Value != "Basic YWRtaW46c2VjcmV0" {
+		t.Errorf("unexpected headers %+v", tool.RequestTemplate.Headers)
+	}
+
+	for _, command := range []string{
+		"curl -X GET",
+		"curl -d @body.json https://api.example.com",
+		"curl -u '{{user}}:{{password}}' https://api.example.com",
+		"curl --proxy http://proxy https://api.example.com",
+	} {
+		if _, err := parseCurl(command, "", "", ""); err == nil {
+			t.Errorf("expected error for %q", command)
+		}
+	}
+}
+
+func TestCurlToolHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(map[string]string{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"query":  r.URL.RawQuery,
+			"type":   r.Header.Get("Content-Type"),
+			"token":  r.Header.Get("X-Token"),
+			"body":   string(body),
+		})
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		command string
+		args    map[string]any
+		want    map[string]string
+	}{
+		{
+			`curl http://localhost/items/{{id}} -H 'X-Token: {{token}}' -G -d 'page={{page:integer}}' -d size=10`,
+			map[string]any{"id": "a b", "token": "t1", "page": 2.0},
+			map[string]string{"method": "GET", "path": "/items/a b", "query": "page=2&size=10", "token": "t1"},
+		},
+		{
+			`curl http://localhost/search --data-urlencode 'q={{q}}' --data-urlencode 'lang=zh cn'`,
+			map[string]any{"q": "a&b"},
+			map[string]string{"method": "POST", "type": "application/x-www-form-urlencoded", "body": "q=a%26b&lang=zh+cn"},
+		},
+		{
+			`curl -X PATCH http://localhost/items/1 -H 'Content-Type: application/json' -d '{"name": "{{name}}", "tags": {{tags:array}}}'`,
+			map[string]any{"name": `x"y`, "tags": []any{"a"}},
+			map[string]string{"method": "PATCH", "type": "application/json", "body": `{"name": "x\"y", "tags": ["a"]}`},
+		},
+	}
+	for _, tt := range tests {
+		tool, err := parseCurl(tt.command, "", upstream.URL, "")
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", tt.command, err)
+		}
+		a, err := newAdapter(&Config{Tools: []ToolConfig{tool}})
+		if err != nil {
+			t.Fatalf("failed to create adapter: %v", err)
+		}
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = tt.args
+		result, err := a.routes[0].handle(context.Background(), request)
+		if err != nil || result.IsError {
+			t.Fatalf("%s: unexpected result %v %+v", tool.Name, err, result)
+		}
+		var got map[string]string
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+			t.Fatalf("%s: failed to decode result: %v", tool.Name, err)
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("%s %s: expected %q, got %q", tool.Name, k, v, got[k])
+			}
+		}
+	}
+}
+
+func TestCurlCommand(t *testing.T) {
+	var out bytes.Buffer
+	if err := curlCommand([]string{"-name", "get_user", "-description", "Get a user", "curl", "https://api.example.com/users/{{id:integer}}"}, &out); err != nil {
+		t.Fatalf("curl command failed: %v", err)
+	}
+	cfg, err := parseConfig(out.Bytes())
+	if err != nil {
+		t.Fatalf("failed to load generated config %s: %v", out.String(), err)
+	}
+	if len(cfg.Tools) != 1 || cfg.Tools[0].Name != "get_user" || cfg.Tools[0].Description != "Get a user" || cfg.Tools[0].Args[0].Type != "integer" {
+		t.Errorf("unexpected config %s", out.String())
+	}
+	if strings.Contains(out.String(), "false") || strings.Contains(out.String(), "0s") {
+		t.Errorf("expected empty values to be pruned: %s", out.String())
+	}
+}
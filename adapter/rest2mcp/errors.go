@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxErrorBody 是错误结果中保留的响应体长度，避免把上游的错误页面整个交给模型
+const maxErrorBody = 1024
+
+var errorKeyPattern = regexp.MustCompile(`^([1-5][0-9][0-9]|[1-5]xx|default)$`)
+
+// parseErrorTemplates 解析 responseTemplate.errors，key 为状态码（404）、状态码段（4xx）或 default
+func parseErrorTemplates(toolName string, errors map[string]string) (map[string]*template.Template, error) {
+	out := make(map[string]*template.Template, len(errors))
+	for key, text := range errors {
+		if !errorKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("tool %s: invalid error key %q, expected a status code like 404, a class like 4xx or default", toolName, key)
+		}
+		tmpl, err := parseTemplate(toolName+".errors."+key, text, false)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = tmpl
+	}
+	return out, nil
+}
+
+// errorResult 把上游的非 2xx 响应转换成 IsError 的 tool 结果，而不是 json-rpc 错误，
+// 这样模型能看到 "404: order not found" 之类的信息并修正调用。
+// 错误信息模板的数据为 {"status", "reason", "body", "args"}，body 为 json 时已解码
+func (r *route) errorResult(status int, body []byte, args map[string]any) (*mcp.CallToolResult, error) {
+	reason := http.StatusText(status)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "upstream error: %d %s", status, reason)
+
+	if tmpl := r.errorTemplate(status); tmpl != nil {
+		var decoded any = string(body)
+		var v any
+		if json.Unmarshal(body, &v) == nil {
+			decoded = v
+		}
+		message, err := render(tmpl, map[string]any{
+			"status": status,
+			"reason": reason,
+			"body":   decoded,
+			"args":   args,
+		})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&sb, "\nmessage: %s", message)
+	}
+
+	if trimmed := strings.TrimSpace(string(body)); trimmed != "" {
+		fmt.Fprintf(&sb, "\nbody: %s", truncate(trimmed, maxErrorBody))
+	}
+	return mcp.NewToolResultError(sb.String()), nil
+}
+
+// errorTemplate 依次按状态码、状态码段和 default 查找错误信息模板
+func (r *route) errorTemplate(status int) *template.Template {
+	for _, key := range []string{strconv.Itoa(status), fmt.Sprintf("%dxx", status/100), "default"} {
+		if tmpl, ok := r.errors[key]; ok {
+			return tmpl
+		}
+	}
+	return nil
+}
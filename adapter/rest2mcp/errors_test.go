@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestUpstreamErrorResult(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orders/missing":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"no such order"}`))
+		case "/orders/locked":
+			http.Error(w, "locked", http.StatusConflict)
+		case "/orders/boom":
+			http.Error(w, strings.Repeat("stack trace ", 200), http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	defer upstream.Close()
+
+	r, err := newRoute(ToolConfig{
+		Name: "get_order",
+		Args: []ArgConfig{{Name: "id", Required: true, Position: "path"}},
+		RequestTemplate: RequestTemplate{
+			URL: upstream.URL + "/orders/{id}",
+		},
+		ResponseTemplate: ResponseTemplate{
+			Errors: map[string]string{
+				"404":     "order {{.args.id}} not found ({{.body.error}})",
+				"4xx":     "order {{.args.id}} can not be read: {{.reason}}",
+				"default": "order service is unavailable, try again later",
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create route: %v", err)
+	}
+
+	tests := []struct {
+		id   string
+		want []string
+	}{
+		{"missing", []string{"upstream error: 404 Not Found", "message: order missing not found (no such order)", `body: {"error":"no such order"}`}},
+		{"locked", []string{"upstream error: 409 Conflict", "message: order locked can not be read: Conflict", "body: locked"}},
+		{"boom", []string{"upstream error: 500 Internal Server Error", "message: order service is unavailable, try again later", "...(truncated)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = map[string]any{"id": tt.id}
+			result, err := r.handle(context.Background(), request)
+			if err != nil {
+				t.Fatalf("expected tool error result, got protocol error: %v", err)
+			}
+			if !result.IsError {
+				t.Error("expected IsError to be true")
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			for _, want := range tt.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("expected %q in result:\n%s", want, text)
+				}
+			}
+			if len(text) > maxErrorBody+200 {
+				t.Errorf("expected body to be trimmed, got %d bytes", len(text))
+			}
+		})
+	}
+
+	// 传输错误仍然是协议错误
+	upstream.Close()
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"id": "1"}
+	if _, err := r.handle(context.Background(), request); err == nil {
+		t.Error("expected protocol error when upstream is unreachable")
+	}
+
+	if _, err := newRoute(ToolConfig{Name: "bad", RequestTemplate: RequestTemplate{URL: "http://localhost"}, ResponseTemplate: ResponseTemplate{Errors: map[string]string{"40x": "x"}}}, nil); err == nil {
+		t.Error("expected error for invalid error key")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// explainSuffix 是 explain tool 的名称后缀，server.explain 为 true 时每个 tool 都有一个 <tool>__explain
+const explainSuffix = "__explain"
+
+// maskedValue 代替请求中的凭证
+const maskedValue = "****"
+
+// secretName 匹配需要遮盖的 header 和 query 参数名，认证注入的值无论名称都会遮盖
+var secretName = regexp.MustCompile(`(?i)auth|token|secret|passw|api[-_]?key|cookie|session|signature`)
+
+// explanation 是 dry run 时返回给模型的请求
+type explanation struct {
+	DryRun  bool              `json:"dryRun"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body 为 json 请求体本身，其他文本请求体为字符串
+	Body any `json:"body,omitempty"`
+}
+
+type dryRunKey struct{}
+
+// withDryRun 标记这次调用只构造请求，oauth2 不会去获取 token，文件参数中的 resource 不会被读取
+func withDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+func isDryRun(ctx context.Context) bool {
+	dry, _ := ctx.Value(dryRunKey{}).(bool)
+	return dry
+}
+
+// explainTool 是 tool 的 explain 版本，入参相同，返回构造好的 http 请求而不发送
+func (r *route) explainTool() mcp.Tool {
+	tool := r.mcpTool()
+	tool.Name = r.Name + explainSuffix
+	tool.Description = fmt.Sprintf("Dry run of %s: returns the HTTP request that %s would send for these arguments (method, url, headers with credentials masked and body) without sending it. Use it to check the arguments when %s fails.", r.Name, r.Name, r.Name)
+	return tool
+}
+
+// handleExplain 是 explain tool 的 handler，入参的默认值和校验与 handle 相同
+func (r *route) handleExplain(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := r.withDefaults(request.GetArguments())
+	if err := r.validateArgs(args); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if r.pagination != nil {
+		if cursor := r.pagination.initial(args); cursor != "" {
+			ctx = withPageRequest(ctx, r.pagination.request(cursor))
+		}
+	}
+	return r.explain(ctx, args)
+}
+
+// explain 按入参构造请求并注入认证信息，但不经过缓存、限流和熔断，也不发送，
+// 返回请求的 method、url、header 和 body，其中的凭证被遮盖
+func (r *route) explain(ctx context.Context, args map[string]any) (*mcp.CallToolResult, error) {
+	// dry run 不执行任何请求，包括读取作为文件上传的 resource
+	ctx = withDryRun(ctx)
+	var inst *instance
+	if r.upstream != nil {
+		inst = r.upstream.pick(args)
+	}
+	req, err := r.buildRequest(ctx, args, inst)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	// 认证前后的差异就是注入的凭证
+	header := req.Header.Clone()
+	query := req.URL.Query()
+	if err := r.authenticate(ctx, req); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	e := explanation{DryRun: true, Method: req.Method, URL: maskURL(req.URL, query)}
+	if len(req.Header) > 0 {
+		e.Headers = map[string]string{}
+		for name, values := range req.Header {
+			value := strings.Join(values, ", ")
+			if secretName.MatchString(name) || value != strings.Join(header[name], ", ") {
+				value = maskHeader(name, value)
+			}
+			e.Headers[name] = value
+		}
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %v", err)
+		}
+		switch {
+		case len(body) == 0:
+		case json.Valid(body):
+			e.Body = json.RawMessage(body)
+		case utf8.Valid(body):
+			e.Body = string(body)
+		default:
+			e.Body = fmt.Sprintf("<%d bytes of binary data>", len(body))
+		}
+	}
+	b, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %v", err)
+	}
+	return r.textResult(string(b)), nil
+}
+
+// maskURL 遮盖 url 中的用户信息、名称像凭证的 query 参数和认证时加入的 query 参数，before 是认证前的 query
+func maskURL(u *url.URL, before url.Values) string {
+	masked := *u
+	if masked.User != nil {
+		masked.User = url.User(maskedValue)
+	}
+	q := masked.Query()
+	changed := false
+	for name, values := range q {
+		if secretName.MatchString(name) || strings.Join(values, "&") != strings.Join(before[name], "&") {
+			q[name] = []string{maskedValue}
+			changed = true
+		}
+	}
+	if changed {
+		masked.RawQuery = q.Encode()
+	}
+	// 遮盖用的 * 不需要转义，保持可读
+	return strings.ReplaceAll(masked.String(), "%2A", "*")
+}
+
+// maskHeader 遮盖 header 的值，Authorization 保留认证方式，如 Bearer ****
+func maskHeader(name, value string) string {
+	if strings.EqualFold(name, "Authorization") || strings.EqualFold(name, "Proxy-Authorization") {
+		if scheme, _, ok := strings.Cut(value, " "); ok {
+			return scheme + " " + maskedValue
+		}
+	}
+	return maskedValue
+}
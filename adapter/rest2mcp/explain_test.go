@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// listTools 通过 tools/list 返回 mcp server 注册的 tools
+func listTools(s *server.MCPServer) map[string]mcp.Tool {
+	req, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/list"})
+	resp, _ := s.HandleMessage(context.Background(), req).(mcp.JSONRPCResponse)
+	b, _ := json.Marshal(resp.Result)
+	var result struct {
+		Tools []mcp.Tool `json:"tools"`
+	}
+	json.Unmarshal(b, &result)
+	tools := map[string]mcp.Tool{}
+	for _, tool := range result.Tools {
+		tools[tool.Name] = tool
+	}
+	return tools
+}
+
+func TestExplain(t *testing.T) {
+	// dry run 不会请求上游和 token 接口
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	cfg, err := parseConfig([]byte(fmt.Sprintf(`
+server:
+  explain: true
+upstreams:
+  orders:
+    baseURL: %[1]s/api
+    auth: {type: apiKey, in: query, name: key, value: s3cret}
+  billing:
+    baseURL: %[1]s
+    auth: {type: oauth2, tokenURL: %[1]s/token, clientID: client, clientSecret: s3cret}
+tools:
+  - name: update_order
+    args:
+      - {name: id, type: integer, required: true, position: path}
+      - {name: note, type: string}
+      - {name: X-Session-Id, type: string, position: header}
+      - {name: access_token, type: string, position: query}
+    requestTemplate:
+      upstream: orders
+      url: /orders/{id}
+      method: PATCH
+      headers:
+        - {key: X-Trace, value: abc}
+  - name: invoices
+    args:
+      - {name: month, type: string, position: query}
+    requestTemplate: {upstream: billing, url: /invoices}
+`, upstream.URL)))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	s := server.NewMCPServer("test", "1.0.0")
+	a.register(s)
+	tools := listTools(s)
+	explain, ok := tools["update_order__explain"]
+	if !ok || len(tools) != 4 {
+		t.Fatalf("explain tools are not registered: %v", tools)
+	}
+	if !reflect.DeepEqual(explain.InputSchema.Required, []string{"id"}) || len(explain.InputSchema.Properties) != 4 {
+		t.Errorf("explain tool should have the same input schema, got %v", explain.InputSchema)
+	}
+
+	tests := []struct {
+		route   *route
+		args    map[string]any
+		isError bool
+		want    string
+	}{
+		{a.routes[0], map[string]any{"id": 7.0, "note": "rush", "X-Session-Id": "abc", "access_token": "t"}, false, fmt.Sprintf(`{
+			"dryRun": true,
+			"method": "PATCH",
+			"url": "%s/api/orders/7?access_token=****&key=****",
+			"headers": {"Content-Type": "application/json", "X-Session-Id": "****", "X-Trace": "abc"},
+			"body": {"note": "rush"}
+		}`, upstream.URL)},
+		{a.routes[1], map[string]any{"month": "2024-01"}, false, fmt.Sprintf(`{
+			"dryRun": true,
+			"method": "GET",
+			"url": "%s/invoices?month=2024-01",
+			"headers": {"Authorization": "Bearer ****"}
+		}`, upstream.URL)},
+		{a.routes[0], map[string]any{"id": "x"}, true, "id: expected integer, got string"},
+	}
+	for _, tt := range tests {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = tt.args
+		result, err := tt.route.handleExplain(context.Background(), request)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.route.Name, err)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		if result.IsError != tt.isError {
+			t.Fatalf("%s: expected isError %v, got %s", tt.route.Name, tt.isError, text)
+		}
+		if tt.isError {
+			if !strings.Contains(text, tt.want) {
+				t.Errorf("%s: expected %q, got %s", tt.route.Name, tt.want, text)
+			}
+			continue
+		}
+		var got, want any
+		json.Unmarshal([]byte(text), &got)
+		if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+			t.Fatalf("invalid expectation: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %s, got %s", tt.route.Name, tt.want, text)
+		}
+		if strings.Contains(text, "s3cret") {
+			t.Errorf("%s: credentials are not masked: %s", tt.route.Name, text)
+		}
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("expected no upstream requests, got %d", n)
+	}
+
+	cfg.Tools = append(cfg.Tools, ToolConfig{Name: "invoices__explain", RequestTemplate: RequestTemplate{URL: "/x", Upstream: "billing"}})
+	if _, err := newAdapter(cfg); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("expected reserved name error, got %v", err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer upstream.Close()
+
+	cfg, err := parseConfig([]byte(`
+server:
+  dryRun: true
+tools:
+  - name: create_user
+    args:
+      - {name: name, type: string, required: true}
+    requestTemplate:
+      url: ` + upstream.URL + `/users
+      method: POST
+      argsToFormBody: true
+      headers:
+        - {key: Authorization, value: "Basic {{.args.name}}"}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	s := server.NewMCPServer("test", "1.0.0")
+	a.register(s)
+	if _, ok := listTools(s)["create_user__explain"]; ok {
+		t.Errorf("explain tools should only be registered with server.explain")
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"name": "ann"}
+	result, err := a.routes[0].handle(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var got explanation
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("failed to decode explanation: %v", err)
+	}
+	want := explanation{
+		DryRun:  true,
+		Method:  "POST",
+		URL:     upstream.URL + "/users",
+		Headers: map[string]string{"Authorization": "Basic ****", "Content-Type": "application/x-www-form-urlencoded"},
+		Body:    "name=ann",
+	}
+	if result.IsError || !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("expected no upstream requests, got %d", n)
+	}
+}
+
+func TestExplainResourceFile(t *testing.T) {
+	// 作为文件上传的 resource 在 dry run 时不会被读取
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte("%PDF-stored"))
+	}))
+	defer upstream.Close()
+
+	cfg, err := parseConfig([]byte(`
+server:
+  dryRun: true
+upstreams:
+  docs:
+    baseURL: ` + upstream.URL + `
+resources:
+  - name: doc
+    uri: "docs://{id}"
+    requestTemplate: {upstream: docs, url: "/docs/{id}"}
+tools:
+  - name: upload
+    args:
+      - name: document
+        required: true
+        file: {filename: report.pdf}
+    requestTemplate:
+      upstream: docs
+      url: /upload
+      method: POST
+      argsToMultipartBody: true
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"document": "docs://42"}
+	result, err := a.routes[0].handle(context.Background(), request)
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected result %v %+v", err, result)
+	}
+	var got explanation
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("failed to decode explanation: %v", err)
+	}
+	body, _ := got.Body.(string)
+	if !strings.Contains(body, `filename="report.pdf"`) || !strings.Contains(body, "<resource docs://42>") {
+		t.Errorf("expected resource placeholder in body, got %q", body)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("expected no upstream requests, got %d", n)
+	}
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// GraphQLConfig 把 tool 映射成 GraphQL 文档中的一个 query 或 mutation，
+// 未声明 position 的参数作为 variables 发送，url 为 GraphQL 接口地址
+type GraphQLConfig struct {
+	// Query 是 GraphQL 文档，可以包含多个操作和 fragment
+	Query string `json:"query"`
+	// OperationName 是要执行的操作名，文档中只有一个操作时可以省略
+	OperationName string `json:"operationName"`
+}
+
+// graphqlRequest 是解析好的 GraphQL 操作
+type graphqlRequest struct {
+	query         string
+	operationName string
+	// kind 是 query 或 mutation，query 和 GET 一样可以重试
+	kind string
+}
+
+func newGraphQLRequest(t ToolConfig) (*graphqlRequest, error) {
+	g := t.GraphQL
+	if g == nil {
+		return nil, nil
+	}
+	rt := t.RequestTemplate
+	if strings.TrimSpace(g.Query) == "" {
+		return nil, fmt.Errorf("tool %s: graphql.query is required", t.Name)
+	}
+	if rt.Method != http.MethodPost {
+		return nil, fmt.Errorf("tool %s: graphql requests must use POST", t.Name)
+	}
+	if rt.Body != "" || rt.BodyArg != "" || rt.ArgsToUrlParam || rt.ArgsToFormBody || rt.ArgsToMultipartBody {
+		return nil, fmt.Errorf("tool %s: graphql can not be used with body, bodyArg, argsToUrlParam, argsToFormBody or argsToMultipartBody", t.Name)
+	}
+	if t.Async != nil || t.Stream != nil || t.Pagination != nil {
+		return nil, fmt.Errorf("tool %s: graphql can not be used with async, stream or pagination", t.Name)
+	}
+	ops, err := graphqlOperations(g.Query)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %v", t.Name, err)
+	}
+	var op *graphqlOperation
+	for i := range ops {
+		if g.OperationName == "" || ops[i].name == g.OperationName {
+			if op != nil {
+				return nil, fmt.Errorf("tool %s: graphql.operationName is required when the document has more than one operation", t.Name)
+			}
+			op = &ops[i]
+		}
+	}
+	switch {
+	case op == nil && g.OperationName != "":
+		return nil, fmt.Errorf("tool %s: operation %s is not defined in graphql.query", t.Name, g.OperationName)
+	case op == nil:
+		return nil, fmt.Errorf("tool %s: graphql.query has no operation", t.Name)
+	case op.kind == "subscription":
+		return nil, fmt.Errorf("tool %s: graphql subscriptions are not supported", t.Name)
+	}
+	// 文档中只有一个具名操作时也带上 operationName，便于上游记录日志
+	return &graphqlRequest{query: g.Query, operationName: op.name, kind: op.kind}, nil
+}
+
+// body 生成 GraphQL 请求体，入参作为 variables
+func (g *graphqlRequest) body(variables map[string]any) ([]byte, error) {
+	payload := map[string]any{"query": g.query, "variables": variables}
+	if g.operationName != "" {
+		payload["operationName"] = g.operationName
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal graphql request: %v", err)
+	}
+	return body, nil
+}
+
+// graphqlError 是 GraphQL 响应中的 errors 数组，作为 tool 错误交给模型
+type graphqlError struct {
+	errors []graphqlErrorItem
+	// data 是出错时已经返回的部分数据
+	data json.RawMessage
+}
+
+type graphqlErrorItem struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path"`
+	Extensions map[string]any `json:"extensions"`
+}
+
+func (e *graphqlError) Error() string {
+	lines := make([]string, 0, len(e.errors)+1)
+	for _, item := range e.errors {
+		line := "graphql error: " + item.Message
+		var details []string
+		if len(item.Path) > 0 {
+			path := make([]string, len(item.Path))
+			for i, p := range item.Path {
+				path[i] = fmt.Sprint(p)
+			}
+			details = append(details, "path "+strings.Join(path, "."))
+		}
+		if code, ok := item.Extensions["code"]; ok {
+			details = append(details, fmt.Sprintf("code %v", code))
+		}
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	if len(e.data) > 0 && string(e.data) != "null" {
+		lines = append(lines, "partial data: "+truncate(string(e.data), 512))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unwrap 解析 GraphQL 响应：errors 不为空时返回 graphqlError，否则返回只包含 data 的响应，
+// 响应模板的 select 和 body 都作用于 data。响应体不是 GraphQL 响应时（如网关返回的错误页）原样返回，按状态码处理
+func (g *graphqlRequest) unwrap(resp *upstreamResponse) (*upstreamResponse, error) {
+	var body struct {
+		Data   json.RawMessage    `json:"data"`
+		Errors []graphqlErrorItem `json:"errors"`
+	}
+	if err := json.Unmarshal(resp.body, &body); err != nil || body.Data == nil && body.Errors == nil {
+		return resp, nil
+	}
+	if len(body.Errors) > 0 {
+		return nil, &graphqlError{errors: body.Errors, data: body.Data}
+	}
+	unwrapped := *resp
+	unwrapped.body = body.Data
+	unwrapped.header = resp.header.Clone()
+	unwrapped.header.Set("Content-Type", "application/json")
+	return &unwrapped, nil
+}
+
+// graphqlOperation 是文档中定义的一个操作
+type graphqlOperation struct {
+	kind string
+	name string
+}
+
+// graphqlOperations 找出文档顶层定义的操作，{ ... } 形式的简写是匿名 query。
+// 只扫描顶层的关键字，跳过字符串、注释和花括号、圆括号中的内容，不校验文档的语法
+func graphqlOperations(doc string) ([]graphqlOperation, error) {
+	var ops []graphqlOperation
+	depth := 0
+	// pending 表示已经读到 query 等关键字，还没有读到它的选择集
+	pending := false
+	for i := 0; i < len(doc); {
+		c := doc[i]
+		switch {
+		case c == '#':
+			for i < len(doc) && doc[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(doc[i:], `"""`):
+			end := strings.Index(doc[i+3:], `"""`)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated block string in graphql document")
+			}
+			i += end + 6
+		case c == '"':
+			i++
+			for i < len(doc) && doc[i] != '"' {
+				if doc[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(doc) {
+				return nil, fmt.Errorf("unterminated string in graphql document")
+			}
+			i++
+		case c == '{' || c == '(':
+			if c == '{' && depth == 0 {
+				if !pending {
+					ops = append(ops, graphqlOperation{kind: "query"})
+				}
+				pending = false
+			}
+			depth++
+			i++
+		case c == '}' || c == ')':
+			if depth--; depth < 0 {
+				return nil, fmt.Errorf("unbalanced brackets in graphql document")
+			}
+			i++
+		case isGraphQLNameChar(c):
+			start := i
+			for i < len(doc) && isGraphQLNameChar(doc[i]) {
+				i++
+			}
+			if depth > 0 {
+				continue
+			}
+			word := doc[start:i]
+			switch {
+			case pending:
+				if ops[len(ops)-1].name == "" {
+					ops[len(ops)-1].name = word
+				}
+			case word == "query" || word == "mutation" || word == "subscription":
+				ops = append(ops, graphqlOperation{kind: word})
+				pending = true
+			case word == "fragment":
+				// fragment 的名称和类型条件都不是操作，跳到它的选择集
+				for i < len(doc) && doc[i] != '{' {
+					i++
+				}
+				depth++
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced brackets in graphql document")
+	}
+	return ops, nil
+}
+
+func isGraphQLNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// graphqlSchema 是 introspection 查询结果中用到的部分
+type graphqlSchema struct {
+	QueryType    *graphqlNamed `json:"queryType"`
+	MutationType *graphqlNamed `json:"mutationType"`
+	Types        []graphqlType `json:"types"`
+}
+
+type graphqlNamed struct {
+	Name string `json:"name"`
+}
+
+type graphqlType struct {
+	Kind        string              `json:"kind"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Fields      []graphqlField      `json:"fields"`
+	InputFields []graphqlInputValue `json:"inputFields"`
+	EnumValues  []graphqlNamed      `json:"enumValues"`
+}
+
+type graphqlField struct {
+	Name              string              `json:"name"`
+	Description       string              `json:"description"`
+	Args              []graphqlInputValue `json:"args"`
+	Type              graphqlTypeRef      `json:"type"`
+	IsDeprecated      bool                `json:"isDeprecated"`
+	DeprecationReason string              `json:"deprecationReason"`
+}
+
+type graphqlInputValue struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Type        graphqlTypeRef `json:"type"`
+}
+
+type graphqlTypeRef struct {
+	Kind   string          `json:"kind"`
+	Name   string          `json:"name"`
+	OfType *graphqlTypeRef `json:"ofType"`
+}
+
+// String 返回变量声明中的类型，如 [ID!]!
+func (t graphqlTypeRef) String() string {
+	switch {
+	case t.Kind == "NON_NULL" && t.OfType != nil:
+		return t.OfType.String() + "!"
+	case t.Kind == "LIST" && t.OfType != nil:
+		return "[" + t.OfType.String() + "]"
+	}
+	return t.Name
+}
+
+// named 去掉 NON_NULL 和 LIST，返回最内层的类型名
+func (t graphqlTypeRef) named() string {
+	for t.OfType != nil {
+		t = *t.OfType
+	}
+	return t.Name
+}
+
+// graphqlSelectionDepth 是生成的选择集最多展开的对象层数
+const graphqlSelectionDepth = 2
+
+// loadGraphQLTools 读取 GraphQL introspection 查询的结果（json），为 Query 和 Mutation 的每个顶层字段生成一个 ToolConfig。
+// 字段的参数变成 tool 的入参和 GraphQL 变量，返回值的选择集包含两层以内不需要参数的字段；baseURL 是 GraphQL 接口地址，
+// 指定了上游时默认为 /graphql
+func loadGraphQLTools(path, baseURL, upstream string) ([]ToolConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read graphql schema: %v", err)
+	}
+	return parseGraphQLSchema(data, baseURL, upstream)
+}
+
+func parseGraphQLSchema(data []byte, baseURL, upstream string) ([]ToolConfig, error) {
+	// 兼容完整的 GraphQL 响应 {"data": {"__schema": ...}} 和只有 {"__schema": ...} 的文件
+	var doc struct {
+		Data struct {
+			Schema *graphqlSchema `json:"__schema"`
+		} `json:"data"`
+		Schema *graphqlSchema `json:"__schema"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse graphql schema: %v", err)
+	}
+	schema := doc.Schema
+	if schema == nil {
+		schema = doc.Data.Schema
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("failed to parse graphql schema: __schema not found, expected the result of an introspection query")
+	}
+	endpoint := baseURL
+	if endpoint == "" && upstream != "" {
+		endpoint = "/graphql"
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("graphql schema does not contain the endpoint, please set the base url")
+	}
+
+	types := map[string]graphqlType{}
+	for _, t := range schema.Types {
+		types[t.Name] = t
+	}
+	var tools []ToolConfig
+	used := map[string]bool{}
+	for _, root := range []struct {
+		kind string
+		typ  *graphqlNamed
+	}{{"query", schema.QueryType}, {"mutation", schema.MutationType}} {
+		if root.typ == nil {
+			continue
+		}
+		for _, field := range types[root.typ.Name].Fields {
+			if field.IsDeprecated {
+				continue
+			}
+			tool := graphqlTool(root.kind, field, types)
+			tool.Name = uniqueName(tool.Name, used)
+			tool.RequestTemplate.URL = endpoint
+			tool.RequestTemplate.Upstream = upstream
+			tools = append(tools, tool)
+		}
+	}
+	return tools, nil
+}
+
+// graphqlTool 为一个顶层字段生成 tool，结果只返回这个字段的值
+func graphqlTool(kind string, field graphqlField, types map[string]graphqlType) ToolConfig {
+	description := field.Description
+	if description == "" {
+		description = fmt.Sprintf("GraphQL %s %s", kind, field.Name)
+	}
+	tool := ToolConfig{
+		Name:             field.Name,
+		Description:      description,
+		RequestTemplate:  RequestTemplate{Method: http.MethodPost},
+		ResponseTemplate: ResponseTemplate{Select: "$." + field.Name},
+	}
+	var vars, args []string
+	for _, arg := range field.Args {
+		vars = append(vars, "$"+arg.Name+": "+arg.Type.String())
+		args = append(args, arg.Name+": $"+arg.Name)
+		schema := graphqlArgSchema(arg.Type, types, map[string]bool{})
+		a := ArgConfig{Name: arg.Name, Description: arg.Description, Required: arg.Type.Kind == "NON_NULL"}
+		a.Type, _ = schema["type"].(string)
+		a.Enum, _ = schema["enum"].([]any)
+		a.Items, _ = schema["items"].(map[string]any)
+		a.Properties, _ = schema["properties"].(map[string]any)
+		a.RequiredProperties, _ = schema["required"].([]string)
+		tool.Args = append(tool.Args, a)
+	}
+
+	var query strings.Builder
+	query.WriteString(kind + " " + field.Name)
+	if len(vars) > 0 {
+		query.WriteString("(" + strings.Join(vars, ", ") + ")")
+	}
+	query.WriteString(" {\n  " + field.Name)
+	if len(args) > 0 {
+		query.WriteString("(" + strings.Join(args, ", ") + ")")
+	}
+	query.WriteString(graphqlSelection(field.Type.named(), types, graphqlSelectionDepth, "  "))
+	query.WriteString("\n}")
+	tool.GraphQL = &GraphQLConfig{Query: query.String(), OperationName: field.Name}
+	return tool
+}
+
+// graphqlArgSchema 把 GraphQL 输入类型转换成 json schema，seen 用于避免递归的输入类型无限展开
+func graphqlArgSchema(t graphqlTypeRef, types map[string]graphqlType, seen map[string]bool) map[string]any {
+	switch t.Kind {
+	case "NON_NULL":
+		if t.OfType != nil {
+			return graphqlArgSchema(*t.OfType, types, seen)
+		}
+	case "LIST":
+		if t.OfType != nil {
+			return map[string]any{"type": "array", "items": graphqlArgSchema(*t.OfType, types, seen)}
+		}
+	}
+	switch t.Name {
+	case "Int":
+		return map[string]any{"type": "integer"}
+	case "Float":
+		return map[string]any{"type": "number"}
+	case "Boolean":
+		return map[string]any{"type": "boolean"}
+	case "String", "ID":
+		return map[string]any{"type": "string"}
+	}
+	def := types[t.Name]
+	switch def.Kind {
+	case "ENUM":
+		values := make([]any, len(def.EnumValues))
+		for i, v := range def.EnumValues {
+			values[i] = v.Name
+		}
+		return map[string]any{"type": "string", "enum": values}
+	case "INPUT_OBJECT":
+		if seen[t.Name] {
+			return map[string]any{"type": "object"}
+		}
+		seen[t.Name] = true
+		defer delete(seen, t.Name)
+		properties := map[string]any{}
+		var required []string
+		for _, f := range def.InputFields {
+			p := graphqlArgSchema(f.Type, types, seen)
+			if f.Description != "" {
+				p["description"] = f.Description
+			}
+			properties[f.Name] = p
+			if f.Type.Kind == "NON_NULL" {
+				required = append(required, f.Name)
+			}
+		}
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	// 自定义 scalar 的格式未知，按字符串传递
+	return map[string]any{"type": "string"}
+}
+
+// graphqlSelection 生成对象类型的选择集：标量和枚举字段，以及 depth 层以内的对象字段，跳过必须传参的字段
+func graphqlSelection(name string, types map[string]graphqlType, depth int, indent string) string {
+	def := types[name]
+	if def.Kind != "OBJECT" && def.Kind != "INTERFACE" && def.Kind != "UNION" {
+		return ""
+	}
+	var lines []string
+	for _, f := range def.Fields {
+		if f.IsDeprecated || requiresArgs(f) {
+			continue
+		}
+		switch types[f.Type.named()].Kind {
+		case "OBJECT", "INTERFACE", "UNION":
+			if depth > 1 {
+				if sub := graphqlSelection(f.Type.named(), types, depth-1, indent+"  "); sub != "" {
+					lines = append(lines, f.Name+sub)
+				}
+			}
+		default:
+			lines = append(lines, f.Name)
+		}
+	}
+	// union 和没有可选字段的对象至少返回类型名
+	if len(lines) == 0 {
+		lines = append(lines, "__typename")
+	}
+	inner := indent + "  "
+	return " {\n" + inner + strings.Join(lines, "\n"+inner) + "\n" + indent + "}"
+}
+
+func requiresArgs(f graphqlField) bool {
+	for _, arg := range f.Args {
+		if arg.Type.Kind == "NON_NULL" {
+			return true
+		}
+	}
+	return false
+}
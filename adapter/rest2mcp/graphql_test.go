@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// graphqlStandIn 是测试用的 GraphQL 服务：按 operationName 返回固定的结果，并记录收到的请求
+func graphqlStandIn(t *testing.T, requests *[]map[string]any) *httptest.Server {
+	var flaky atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		*requests = append(*requests, req)
+		vars, _ := req["variables"].(map[string]any)
+		var resp any
+		switch req["operationName"] {
+		case "user", "GetUser":
+			if vars["id"] == "404" {
+				resp = map[string]any{
+					"data":   map[string]any{"user": nil},
+					"errors": []any{map[string]any{"message": "user not found", "path": []any{"user"}, "extensions": map[string]any{"code": "NOT_FOUND"}}},
+				}
+			} else {
+				resp = map[string]any{"data": map[string]any{"user": map[string]any{"id": vars["id"], "name": "Ann", "role": "ADMIN"}}}
+			}
+		case "createUser":
+			input, _ := vars["input"].(map[string]any)
+			resp = map[string]any{"data": map[string]any{"createUser": map[string]any{"id": "2", "name": input["name"], "role": input["role"]}}}
+		case "Flaky":
+			if flaky.Add(1) == 1 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			resp = map[string]any{"data": map[string]any{"ok": true}}
+		default:
+			resp = map[string]any{"errors": []any{map[string]any{"message": "unknown operation"}}}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func callGraphQL(t *testing.T, a *adapter, name string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	for _, r := range a.routes {
+		if r.Name != name {
+			continue
+		}
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = args
+		result, err := r.handle(context.Background(), request)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", name, err)
+		}
+		return result
+	}
+	t.Fatalf("tool %s not found", name)
+	return nil
+}
+
+func TestGraphQLRoute(t *testing.T) {
+	var requests []map[string]any
+	server := graphqlStandIn(t, &requests)
+	a, err := newAdapter(&Config{Tools: []ToolConfig{
+		{
+			Name:            "get_user",
+			Args:            []ArgConfig{{Name: "id", Type: "string", Required: true}},
+			RequestTemplate: RequestTemplate{URL: server.URL + "/graphql"},
+			GraphQL: &GraphQLConfig{
+				Query:         "# users\nquery GetUser($id: ID!) { user(id: $id) { ...fields } }\nmutation Other { x }\nfragment fields on User { id name }",
+				OperationName: "GetUser",
+			},
+			ResponseTemplate: ResponseTemplate{Select: "$.user.name"},
+		},
+		{
+			Name:            "flaky",
+			RequestTemplate: RequestTemplate{URL: server.URL},
+			Retry:           RetryConfig{Attempts: 2, Backoff: Duration(1)},
+			GraphQL:         &GraphQLConfig{Query: "query Flaky { ok }"},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	result := callGraphQL(t, a, "get_user", map[string]any{"id": "1"})
+	if result.IsError || result.Content[0].(mcp.TextContent).Text != "Ann" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if requests[0]["operationName"] != "GetUser" || !reflect.DeepEqual(requests[0]["variables"], map[string]any{"id": "1"}) {
+		t.Errorf("unexpected graphql request %v", requests[0])
+	}
+
+	// errors 数组作为 tool 错误
+	result = callGraphQL(t, a, "get_user", map[string]any{"id": "404"})
+	text := result.Content[0].(mcp.TextContent).Text
+	if !result.IsError || text != "graphql error: user not found (path user, code NOT_FOUND)\npartial data: {\"user\":null}" {
+		t.Errorf("unexpected error result %q", text)
+	}
+
+	// query 使用 POST 也会按 GET 的规则重试
+	result = callGraphQL(t, a, "flaky", nil)
+	if result.IsError || result.Content[0].(mcp.TextContent).Text != `{"ok":true}` {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestGraphQLConfigErrors(t *testing.T) {
+	tests := []struct {
+		tool ToolConfig
+		want string
+	}{
+		{ToolConfig{Name: "a", GraphQL: &GraphQLConfig{Query: "query A { a } query B { b }"}}, "operationName is required"},
+		{ToolConfig{Name: "a", GraphQL: &GraphQLConfig{Query: "query A { a }", OperationName: "B"}}, "operation B is not defined"},
+		{ToolConfig{Name: "a", GraphQL: &GraphQLConfig{Query: "subscription { a }"}}, "subscriptions are not supported"},
+		{ToolConfig{Name: "a", RequestTemplate: RequestTemplate{Method: "GET"}, GraphQL: &GraphQLConfig{Query: "{ a }"}}, "must use POST"},
+		{ToolConfig{Name: "a", RequestTemplate: RequestTemplate{Body: "{}"}, GraphQL: &GraphQLConfig{Query: "{ a }"}}, "can not be used with body"},
+		{ToolConfig{Name: "a", GraphQL: &GraphQLConfig{Query: "query A { a "}}, "unbalanced brackets"},
+	}
+	for _, tt := range tests {
+		tt.tool.RequestTemplate.URL = "http://localhost/graphql"
+		_, err := newAdapter(&Config{Tools: []ToolConfig{tt.tool}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("expected error containing %q, got %v", tt.want, err)
+		}
+	}
+}
+
+func TestGraphQLOperations(t *testing.T) {
+	ops, err := graphqlOperations(`
+		"""description with { brace"""
+		query ($id: ID = "}") { a(id: $id) }
+		fragment f on T { b { c } }
+		mutation Save @log { save }
+		{ d }`)
+	if err != nil {
+		t.Fatalf("failed to parse operations: %v", err)
+	}
+	want := []graphqlOperation{{kind: "query"}, {kind: "mutation", name: "Save"}, {kind: "query"}}
+	if !reflect.DeepEqual(ops, want) {
+		t.Errorf("expected %+v, got %+v", want, ops)
+	}
+}
+
+const testGraphQLSchema = `{"data": {"__schema": {
+  "queryType": {"name": "Query"},
+  "mutationType": {"name": "Mutation"},
+  "types": [
+    {"kind": "OBJECT", "name": "Query", "fields": [
+      {"name": "user", "description": "Get a user by id.", "args": [
+        {"name": "id", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}}
+      ], "type": {"kind": "OBJECT", "name": "User"}},
+      {"name": "legacyUser", "isDeprecated": true, "args": [], "type": {"kind": "OBJECT", "name": "User"}}
+    ]},
+    {"kind": "OBJECT", "name": "Mutation", "fields": [
+      {"name": "createUser", "args": [
+        {"name": "input", "type": {"kind": "NON_NULL", "ofType": {"kind": "INPUT_OBJECT", "name": "UserInput"}}},
+        {"name": "tags", "type": {"kind": "LIST", "ofType": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "String"}}}}
+      ], "type": {"kind": "NON_NULL", "ofType": {"kind": "OBJECT", "name": "User"}}}
+    ]},
+    {"kind": "OBJECT", "name": "User", "fields": [
+      {"name": "id", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}},
+      {"name": "name", "args": [], "type": {"kind": "SCALAR", "name": "String"}},
+      {"name": "role", "args": [], "type": {"kind": "ENUM", "name": "Role"}},
+      {"name": "manager", "args": [], "type": {"kind": "OBJECT", "name": "User"}},
+      {"name": "posts", "args": [{"name": "first", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "Int"}}}], "type": {"kind": "LIST", "ofType": {"kind": "OBJECT", "name": "Post"}}}
+    ]},
+    {"kind": "INPUT_OBJECT", "name": "UserInput", "inputFields": [
+      {"name": "name", "description": "Full name", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "String"}}},
+      {"name": "role", "type": {"kind": "ENUM", "name": "Role"}},
+      {"name": "age", "type": {"kind": "SCALAR", "name": "Int"}}
+    ]},
+    {"kind": "ENUM", "name": "Role", "enumValues": [{"name": "ADMIN"}, {"name": "MEMBER"}]},
+    {"kind": "SCALAR", "name": "ID"},
+    {"kind": "SCALAR", "name": "String"}
+  ]
+}}}`
+
+func TestParseGraphQLSchema(t *testing.T) {
+	tools, err := parseGraphQLSchema([]byte(testGraphQLSchema), "http://localhost/graphql", "")
+	if err != nil {
+		t.Fatalf("failed to parse graphql schema: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Name != "user" || tools[1].Name != "createUser" {
+		t.Fatalf("unexpected tools %+v", tools)
+	}
+
+	user := tools[0]
+	wantQuery := "query user($id: ID!) {\n  user(id: $id) {\n    id\n    name\n    role\n    manager {\n      id\n      name\n      role\n    }\n  }\n}"
+	if user.GraphQL.Query != wantQuery || user.GraphQL.OperationName != "user" || user.Description != "Get a user by id." {
+		t.Errorf("unexpected query tool %q", user.GraphQL.Query)
+	}
+	if !reflect.DeepEqual(user.Args, []ArgConfig{{Name: "id", Type: "string", Required: true}}) || user.ResponseTemplate.Select != "$.user" {
+		t.Errorf("unexpected query tool %+v", user)
+	}
+
+	create := tools[1]
+	if !strings.HasPrefix(create.GraphQL.Query, "mutation createUser($input: UserInput!, $tags: [String!]) {\n  createUser(input: $input, tags: $tags) {") {
+		t.Errorf("unexpected mutation %q", create.GraphQL.Query)
+	}
+	input := create.Args[0]
+	if input.Type != "object" || !input.Required || !reflect.DeepEqual(input.RequiredProperties, []string{"name"}) ||
+		!reflect.DeepEqual(input.Properties["role"], map[string]any{"type": "string", "enum": []any{"ADMIN", "MEMBER"}}) {
+		t.Errorf("unexpected input arg %+v", input)
+	}
+	if tags := create.Args[1]; tags.Type != "array" || tags.Required || !reflect.DeepEqual(tags.Items, map[string]any{"type": "string"}) {
+		t.Errorf("unexpected tags arg %+v", tags)
+	}
+
+	if _, err := parseGraphQLSchema([]byte(testGraphQLSchema), "", ""); err == nil {
+		t.Errorf("expected error without endpoint")
+	}
+	tools, err = parseGraphQLSchema([]byte(testGraphQLSchema), "", "users")
+	if err != nil || tools[0].RequestTemplate.URL != "/graphql" || tools[0].RequestTemplate.Upstream != "users" {
+		t.Errorf("unexpected upstream tool %+v %v", tools, err)
+	}
+}
+
+func TestGraphQLSchemaToolHandler(t *testing.T) {
+	var requests []map[string]any
+	server := graphqlStandIn(t, &requests)
+	tools, err := parseGraphQLSchema([]byte(testGraphQLSchema), server.URL, "")
+	if err != nil {
+		t.Fatalf("failed to parse graphql schema: %v", err)
+	}
+	a, err := newAdapter(&Config{Tools: tools})
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	result := callGraphQL(t, a, "createUser", map[string]any{"input": map[string]any{"name": "Bob", "role": "MEMBER"}})
+	if result.IsError || result.Content[0].(mcp.TextContent).Text != `{"id":"2","name":"Bob","role":"MEMBER"}` {
+		t.Errorf("unexpected result %+v", result)
+	}
+	// 入参按 schema 校验，不合法的调用不会发到上游
+	result = callGraphQL(t, a, "createUser", map[string]any{"input": map[string]any{"name": "Bob", "role": "OWNER"}})
+	if !result.IsError || len(requests) != 1 {
+		t.Errorf("expected validation error, got %+v", result)
+	}
+}
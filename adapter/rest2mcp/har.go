@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"mcp-demo/internal/jsonschema"
+)
+
+// harLog 是 HAR 1.2 文件中用到的部分
+type harLog struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *struct {
+			MimeType string         `json:"mimeType"`
+			Text     string         `json:"text"`
+			Params   []harNameValue `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harNameValue struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	FileName string `json:"fileName"`
+}
+
+// harSkippedContent 是页面资源的响应类型，这些请求不生成 tool
+var harSkippedContent = []string{"text/html", "text/css", "javascript", "image/", "font/"}
+
+// harIDPattern 匹配路径中的数字和 uuid，它们通常是资源 id
+var harIDPattern = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
+
+// loadHARTools 读取 HAR 文件，为每个不同的 method + 路径生成一个 ToolConfig。
+// 路径中的数字和 uuid 变成 path 参数，query 参数和 json、表单请求体的字段变成 tool 的入参；
+// 抓包中的请求头可能含有 cookie 等凭证，只保留其中写了 {{name}} 变量的
+func loadHARTools(path, baseURL, upstream string) ([]ToolConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read har file: %v", err)
+	}
+	return parseHAR(data, baseURL, upstream)
+}
+
+func parseHAR(data []byte, baseURL, upstream string) ([]ToolConfig, error) {
+	var har harLog
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("failed to parse har file: %v", err)
+	}
+
+	var tools []ToolConfig
+	seen := map[string]int{}
+	used := map[string]bool{}
+	for _, entry := range har.Log.Entries {
+		if entry.skipped() {
+			continue
+		}
+		b, err := entry.toolBuilder(baseURL, upstream)
+		if err != nil {
+			return nil, err
+		}
+		// 同一个接口的多次请求合并成一个 tool，参数取并集
+		key := b.tool.RequestTemplate.Method + " " + b.tool.RequestTemplate.URL
+		if i, ok := seen[key]; ok {
+			for _, arg := range b.tool.Args {
+				if tools[i].arg(arg.Name) == nil {
+					arg.Required = false
+					tools[i].Args = append(tools[i].Args, arg)
+				}
+			}
+			continue
+		}
+		seen[key] = len(tools)
+		b.tool.Name = uniqueName(b.tool.Name, used)
+		tools = append(tools, b.tool)
+	}
+	return tools, nil
+}
+
+func (e harEntry) skipped() bool {
+	if e.Request.Method == "OPTIONS" {
+		return true
+	}
+	for _, s := range harSkippedContent {
+		if strings.Contains(e.Response.Content.MimeType, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e harEntry) toolBuilder(baseURL, upstream string) (*toolBuilder, error) {
+	req := e.Request
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid har request url %q: %v", req.URL, err)
+	}
+	// 把路径中的 id 换成变量，变量名取前一段路径，如 /users/42 中的 user_id
+	segments := strings.Split(u.Path, "/")
+	vars := map[string]string{}
+	for i, segment := range segments {
+		if i == 0 || !harIDPattern.MatchString(segment) {
+			continue
+		}
+		name := "id"
+		if prev := strings.TrimSuffix(segments[i-1], "s"); i > 1 && prev != "" && !placeholderPattern.MatchString(prev) {
+			name = argName(prev) + "_id"
+		}
+		vars[name] = segment
+		segments[i] = "{{" + name + "}}"
+	}
+	path := strings.Join(segments, "/")
+
+	name := operationName(req.Method, strings.NewReplacer("{{", "", "}}", "").Replace(path))
+	b := newToolBuilder(name, req.Method+" "+u.Scheme+"://"+u.Host+u.Path, req.Method, vars)
+	if err := b.setURL(u.Scheme+"://"+u.Host+path, baseURL, upstream); err != nil {
+		return nil, err
+	}
+
+	for _, q := range req.QueryString {
+		if placeholderPattern.MatchString(q.Value) {
+			b.param(positionQuery, q.Name, q.Value)
+		} else if argName(q.Name) == q.Name && b.tool.arg(q.Name) == nil {
+			b.addArg(q.Name, positionQuery, inferType(q.Value), false)
+		} else {
+			b.tool.RequestTemplate.Query = append(b.tool.RequestTemplate.Query, ParamConfig{Key: q.Name, Value: q.Value})
+		}
+	}
+	for _, h := range req.Headers {
+		if placeholderPattern.MatchString(h.Value) {
+			b.param(positionHeader, h.Name, h.Value)
+		}
+	}
+
+	post := req.PostData
+	if post == nil {
+		return b, nil
+	}
+	mimeType, _, _ := strings.Cut(post.MimeType, ";")
+	switch {
+	case placeholderPattern.MatchString(post.Text) && strings.Contains(mimeType, "json"):
+		b.jsonBody(post.Text)
+	case placeholderPattern.MatchString(post.Text):
+		b.tool.RequestTemplate.Body = b.template(post.Text, "", true)
+	case strings.Contains(mimeType, "json"):
+		var body any
+		if err := json.Unmarshal([]byte(post.Text), &body); err != nil {
+			return nil, fmt.Errorf("invalid json request body of %s %s: %v", req.Method, req.URL, err)
+		}
+		if fields, ok := body.(map[string]any); ok && len(fields) > 0 {
+			b.jsonFields(fields)
+		} else {
+			b.addArg("body", positionBody, jsonschema.TypeOf(body), true)
+			b.tool.RequestTemplate.BodyArg = "body"
+		}
+	case mimeType == "application/x-www-form-urlencoded":
+		b.tool.RequestTemplate.ArgsToFormBody = true
+		params := post.Params
+		if len(params) == 0 {
+			values, _ := url.ParseQuery(post.Text)
+			for _, k := range sortedKeys(values) {
+				params = append(params, harNameValue{Name: k, Value: values.Get(k)})
+			}
+		}
+		for _, p := range params {
+			b.bodyArg(p.Name, inferType(p.Value))
+		}
+	case mimeType == "multipart/form-data":
+		b.tool.RequestTemplate.ArgsToMultipartBody = true
+		for _, p := range post.Params {
+			if arg := b.bodyArg(p.Name, "string"); arg != nil && p.FileName != "" {
+				arg.File = &FileConfig{}
+			}
+		}
+	default:
+		// 其他类型的请求体整体作为一个参数，原样发送
+		b.tool.RequestTemplate.Body = b.template("{{body}}", "", true)
+		b.tool.RequestTemplate.Headers = append(b.tool.RequestTemplate.Headers, ParamConfig{Key: "Content-Type", Value: post.MimeType})
+	}
+	return b, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testHAR = `{
+  "log": {
+    "version": "1.2",
+    "entries": [
+      {
+        "request": {
+          "method": "GET",
+          "url": "https://shop.example.com/api/orders/1001?status=paid&page=2",
+          "headers": [{"name": "Cookie", "value": "session=secret"}, {"name": "X-Tenant", "value": "{{tenant}}"}],
+          "queryString": [{"name": "status", "value": "paid"}, {"name": "page", "value": "2"}]
+        },
+        "response": {"content": {"mimeType": "application/json"}}
+      },
+      {
+        "request": {
+          "method": "GET",
+          "url": "https://shop.example.com/api/orders/1002?expand=items",
+          "queryString": [{"name": "expand", "value": "items"}]
+        },
+        "response": {"content": {"mimeType": "application/json"}}
+      },
+      {
+        "request": {
+          "method": "POST",
+          "url": "https://shop.example.com/api/orders",
+          "postData": {"mimeType": "application/json; charset=utf-8", "text": "{\"sku\": \"A-1\", \"quantity\": 2, \"gift\": false, \"note-text\": \"\"}"}
+        },
+        "response": {"content": {"mimeType": "application/json"}}
+      },
+      {
+        "request": {
+          "method": "POST",
+          "url": "https://shop.example.com/api/login",
+          "postData": {"mimeType": "application/x-www-form-urlencoded", "text": "user=bob&remember=true"}
+        },
+        "response": {"content": {"mimeType": "application/json"}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://shop.example.com/static/app.js"},
+        "response": {"content": {"mimeType": "application/javascript"}}
+      }
+    ]
+  }
+}`
+
+func TestParseHAR(t *testing.T) {
+	tools, err := parseHAR([]byte(testHAR), "", "shop")
+	if err != nil {
+		t.Fatalf("failed to parse har: %v", err)
+	}
+	if len(tools) != 3 {
+		t.Fatalf("expected 3 tools, got %+v", tools)
+	}
+
+	// 同一个接口的多次请求合并，路径中的 id 变成参数，请求头中的凭证不会被导入
+	get := tools[0]
+	if get.Name != "get_api_orders_order_id" || get.RequestTemplate.URL != "/api/orders/{order_id}" || get.RequestTemplate.Upstream != "shop" {
+		t.Errorf("unexpected tool %+v", get)
+	}
+	wantArgs := []ArgConfig{
+		{Name: "order_id", Type: "integer", Required: true, Position: positionPath},
+		{Name: "status", Type: "string", Position: positionQuery},
+		{Name: "page", Type: "integer", Position: positionQuery},
+		{Name: "tenant", Type: "string", Position: positionTemplate},
+		{Name: "expand", Type: "string", Position: positionQuery},
+	}
+	if !reflect.DeepEqual(get.Args, wantArgs) {
+		t.Errorf("expected args %+v, got %+v", wantArgs, get.Args)
+	}
+	if want := []ParamConfig{{Key: "X-Tenant", Value: "{{.args.tenant}}"}}; !reflect.DeepEqual(get.RequestTemplate.Headers, want) {
+		t.Errorf("unexpected headers %+v", get.RequestTemplate.Headers)
+	}
+
+	// json 请求体的字段按值推断类型，不能作为参数名的字段被跳过
+	types := map[string]string{}
+	for _, arg := range tools[1].Args {
+		types[arg.Name] = arg.Position + ":" + arg.Type
+	}
+	if want := map[string]string{"gift": "body:boolean", "quantity": "body:integer", "sku": "body:string"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("expected json body args %v, got %v", want, types)
+	}
+	if login := tools[2]; !login.RequestTemplate.ArgsToFormBody || len(login.Args) != 2 || login.Args[0].Type != "boolean" {
+		t.Errorf("unexpected form tool %+v", login)
+	}
+
+	// 生成的配置可以直接用来创建 adapter
+	if _, err := newAdapter(&Config{Upstreams: map[string]UpstreamConfig{"shop": {BaseURL: "http://localhost"}}, Tools: tools}); err != nil {
+		t.Errorf("failed to create adapter from har tools: %v", err)
+	}
+}
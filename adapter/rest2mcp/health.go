@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// HealthCheckConfig 描述对上游实例的主动健康检查，失败的实例会被移出负载均衡
+type HealthCheckConfig struct {
+	// Path 是健康检查的路径，返回 2xx 或 3xx 视为健康
+	Path     string   `json:"path"`
+	Interval Duration `json:"interval"`
+	Timeout  Duration `json:"timeout"`
+	// UnhealthyThreshold 是连续失败多少次后摘除，默认 2；HealthyThreshold 是连续成功多少次后恢复，默认 1
+	UnhealthyThreshold int `json:"unhealthyThreshold"`
+	HealthyThreshold   int `json:"healthyThreshold"`
+}
+
+// startHealthChecks 为每个配置了健康检查的上游实例启动探测，ctx 取消时停止
+func (u *upstream) startHealthChecks(ctx context.Context, client *http.Client) {
+	if u.health == nil {
+		return
+	}
+	cfg := *u.health
+	if cfg.Interval <= 0 {
+		cfg.Interval = Duration(10 * time.Second)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = Duration(2 * time.Second)
+	}
+	if cfg.UnhealthyThreshold <= 0 {
+		cfg.UnhealthyThreshold = 2
+	}
+	if cfg.HealthyThreshold <= 0 {
+		cfg.HealthyThreshold = 1
+	}
+	for _, inst := range u.instances {
+		go inst.probeLoop(ctx, client, u.name, cfg)
+	}
+}
+
+func (inst *instance) probeLoop(ctx context.Context, client *http.Client, upstreamName string, cfg HealthCheckConfig) {
+	ticker := time.NewTicker(time.Duration(cfg.Interval))
+	defer ticker.Stop()
+	successes, failures := 0, 0
+	for {
+		if inst.probe(ctx, client, cfg) {
+			successes, failures = successes+1, 0
+			if !inst.healthy.Load() && successes >= cfg.HealthyThreshold {
+				inst.healthy.Store(true)
+				log.Printf("upstream %s: instance %s is healthy again", upstreamName, inst.baseURL)
+			}
+		} else {
+			successes, failures = 0, failures+1
+			if inst.healthy.Load() && failures >= cfg.UnhealthyThreshold {
+				inst.healthy.Store(false)
+				log.Printf("upstream %s: instance %s is unhealthy, removed from rotation", upstreamName, inst.baseURL)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (inst *instance) probe(ctx context.Context, client *http.Client, cfg HealthCheckConfig) bool {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, inst.resolve(cfg.Path), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 400
+}
+
+// instanceStatus 是管理接口返回的实例状态
+type instanceStatus struct {
+	Upstream string `json:"upstream"`
+	BaseURL  string `json:"baseURL"`
+	Healthy  bool   `json:"healthy"`
+	InFlight int64  `json:"inFlight"`
+}
+
+// upstreamsHandler 以 json 输出所有上游实例的健康状态，挂在 /admin/upstreams 上
+func (a *adapter) upstreamsHandler(w http.ResponseWriter, _ *http.Request) {
+	statuses := []instanceStatus{}
+	for name, u := range a.upstreams {
+		for _, inst := range u.instances {
+			statuses = append(statuses, instanceStatus{
+				Upstream: name,
+				BaseURL:  inst.baseURL,
+				Healthy:  inst.healthy.Load(),
+				InFlight: inst.inFlight.Load(),
+			})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Upstream != statuses[j].Upstream {
+			return statuses[i].Upstream < statuses[j].Upstream
+		}
+		return statuses[i].BaseURL < statuses[j].BaseURL
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
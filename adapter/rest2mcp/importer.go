@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"mcp-demo/internal/jsonschema"
+)
+
+// 从 Postman、HAR、curl 导入 tool 的公共逻辑：请求中 {{name}} 形式的变量变成 tool 的入参，
+// 生成的 ToolConfig 与手写配置、OpenAPI 生成的相同
+
+// placeholderPattern 匹配 {{name}} 形式的变量，{{name:integer}} 可以指定参数类型
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.\-]*)\s*(?::\s*(string|integer|number|boolean|array|object)\s*)?\}\}`)
+
+// toolBuilder 逐步填充一个 ToolConfig，同名变量只生成一个参数
+type toolBuilder struct {
+	tool ToolConfig
+	// vars 是已知值的变量，如 Postman 的集合变量，用于替换地址中的变量和推断参数类型
+	vars map[string]string
+	// descriptions 是变量的说明，作为参数的描述
+	descriptions map[string]string
+	// types 是 {{name:type}} 中指定的参数类型，优先于推断的类型
+	types map[string]string
+}
+
+func newToolBuilder(name, description, method string, vars map[string]string) *toolBuilder {
+	if method == "" {
+		method = "GET"
+	}
+	known := make(map[string]string, len(vars))
+	for k, v := range vars {
+		known[k] = v
+	}
+	return &toolBuilder{
+		tool: ToolConfig{
+			Name:            toolName(name),
+			Description:     strings.TrimSpace(description),
+			RequestTemplate: RequestTemplate{Method: strings.ToUpper(method)},
+		},
+		vars:         known,
+		descriptions: map[string]string{},
+		types:        map[string]string{},
+	}
+}
+
+// toolName 把 "Get user by id" 这样的名称转换成 get_user_by_id
+func toolName(name string) string {
+	return strings.Trim(nonNameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// argName 把变量名转换成可以在模板中以 .args.name 引用的参数名
+func argName(name string) string {
+	return strings.Trim(nonNameChars.ReplaceAllString(name, "_"), "_")
+}
+
+// uniqueName 在名称重复时加上序号
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// inferType 按示例值推断参数类型，不是 json 数字、布尔、数组或对象时为 string
+func inferType(example string) string {
+	var v any
+	if err := json.Unmarshal([]byte(example), &v); err != nil || v == nil {
+		return "string"
+	}
+	return jsonschema.TypeOf(v)
+}
+
+// arg 返回同名的参数，不存在时返回 nil
+func (t ToolConfig) arg(name string) *ArgConfig {
+	for i := range t.Args {
+		if t.Args[i].Name == name {
+			return &t.Args[i]
+		}
+	}
+	return nil
+}
+
+// addArg 添加参数并返回参数名，同名参数已存在时保留第一次出现的位置和类型
+func (b *toolBuilder) addArg(variable, position, typ string, required bool) string {
+	name := argName(variable)
+	if t := b.types[variable]; t != "" {
+		typ = t
+	}
+	if arg := b.tool.arg(name); arg != nil {
+		arg.Required = arg.Required || required
+		return name
+	}
+	b.tool.Args = append(b.tool.Args, ArgConfig{
+		Name:        name,
+		Description: b.descriptions[variable],
+		Type:        typ,
+		Required:    required,
+		Position:    position,
+	})
+	return name
+}
+
+// declareTypes 记录文本中 {{name:type}} 指定的参数类型
+func (b *toolBuilder) declareTypes(text string) {
+	for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		if m[2] != "" {
+			b.types[m[1]] = m[2]
+		}
+	}
+}
+
+// varType 按变量的已知值推断类型
+func (b *toolBuilder) varType(variable, fallback string) string {
+	if v, ok := b.vars[variable]; ok && v != "" {
+		return inferType(v)
+	}
+	return fallback
+}
+
+// wholePlaceholder 判断 s 是否整个就是一个变量
+func wholePlaceholder(s string) (string, bool) {
+	m := placeholderPattern.FindStringSubmatch(s)
+	if m == nil || m[0] != s {
+		return "", false
+	}
+	return m[1], true
+}
+
+// template 把文本中的变量替换成 go template，escape 不为空时用它转义参数值，如 queryEscape
+func (b *toolBuilder) template(text, escape string, required bool) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(m string) string {
+		name := b.addArg(placeholderPattern.FindStringSubmatch(m)[1], positionTemplate, "string", required)
+		if escape != "" {
+			return "{{" + escape + " .args." + name + "}}"
+		}
+		return "{{.args." + name + "}}"
+	})
+}
+
+// setURL 设置请求地址。地址开头的变量（如 {{baseUrl}}/users）用已知的值替换；
+// upstream 不为空时只保留路径，baseURL 不为空时代替开头的变量或原来的 scheme 和 host；
+// 路径中的 {{id}} 和 Postman 的 :id 变成 path 参数
+func (b *toolBuilder) setURL(rawURL, baseURL, upstream string) error {
+	rawURL, _, _ = strings.Cut(rawURL, "?")
+	rawURL, _, _ = strings.Cut(rawURL, "#")
+	if loc := placeholderPattern.FindStringIndex(rawURL); loc != nil && loc[0] == 0 {
+		switch {
+		case upstream != "":
+			rawURL = rawURL[loc[1]:]
+		case baseURL != "":
+			rawURL, baseURL = strings.TrimSuffix(baseURL, "/")+rawURL[loc[1]:], ""
+		}
+	}
+	for i := 0; i < 3; i++ {
+		loc := placeholderPattern.FindStringSubmatchIndex(rawURL)
+		if loc == nil || loc[0] != 0 && !strings.HasSuffix(rawURL[:loc[0]], "://") {
+			break
+		}
+		value, ok := b.vars[rawURL[loc[2]:loc[3]]]
+		if !ok {
+			break
+		}
+		rawURL = rawURL[:loc[0]] + strings.TrimSuffix(value, "/") + rawURL[loc[1]:]
+	}
+
+	origin, path := "", rawURL
+	if scheme, rest, ok := strings.Cut(rawURL, "://"); ok {
+		host, p, _ := strings.Cut(rest, "/")
+		origin, path = scheme+"://"+host, "/"+p
+	} else if !strings.HasPrefix(rawURL, "/") {
+		// 没有 scheme 的地址，如 api.example.com/users
+		host, p, _ := strings.Cut(rawURL, "/")
+		origin, path = "https://"+host, "/"+p
+	}
+	switch {
+	case upstream != "":
+		origin = ""
+		b.tool.RequestTemplate.Upstream = upstream
+	case baseURL != "":
+		origin = strings.TrimSuffix(baseURL, "/")
+	case origin == "" || placeholderPattern.MatchString(origin):
+		return fmt.Errorf("tool %s: can not resolve the base url of %q, please set it explicitly", b.tool.Name, rawURL)
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") && len(segment) > 1 {
+			segment = "{{" + segment[1:] + "}}"
+		}
+		segments[i] = placeholderPattern.ReplaceAllStringFunc(segment, func(m string) string {
+			variable := placeholderPattern.FindStringSubmatch(m)[1]
+			return "{" + b.addArg(variable, positionPath, b.varType(variable, "string"), true) + "}"
+		})
+	}
+	b.tool.RequestTemplate.URL = origin + strings.Join(segments, "/")
+	return nil
+}
+
+// param 添加 query 参数或 header：值整个是同名变量时生成对应位置的参数，含有变量时生成模板，否则原样转发
+func (b *toolBuilder) param(position, key, value string) {
+	params := &b.tool.RequestTemplate.Query
+	if position == positionHeader {
+		params = &b.tool.RequestTemplate.Headers
+	}
+	if variable, ok := wholePlaceholder(value); ok && argName(variable) == key && b.tool.arg(key) == nil {
+		b.addArg(variable, position, b.varType(variable, "string"), false)
+		return
+	}
+	*params = append(*params, ParamConfig{Key: key, Value: b.template(value, "", false)})
+}
+
+// jsonBody 把 json 文本中的变量转换成 body 模板：整个字符串都是变量时替换成 {{json .args.name}}，
+// 字符串中的一部分替换成 {{jsonEscape .args.name}}，不在字符串中的变量按已知的值推断类型，默认为 number
+func (b *toolBuilder) jsonBody(text string) {
+	var out bytes.Buffer
+	inString, stringStart := false, 0
+	for i := 0; i < len(text); {
+		if strings.HasPrefix(text[i:], "{{") {
+			if loc := placeholderPattern.FindStringSubmatchIndex(text[i:]); loc != nil && loc[0] == 0 {
+				variable, end := text[i+loc[2]:i+loc[3]], i+loc[1]
+				switch {
+				case inString && stringStart == i-1 && end < len(text) && text[end] == '"':
+					// 整个字符串就是变量，连同引号一起替换
+					out.Truncate(out.Len() - 1)
+					writeAction(&out, "{{json .args."+b.addArg(variable, positionTemplate, "string", false)+"}}")
+					inString, end = false, end+1
+				case inString:
+					writeAction(&out, "{{jsonEscape .args."+b.addArg(variable, positionTemplate, "string", true)+"}}")
+				default:
+					writeAction(&out, "{{json .args."+b.addArg(variable, positionTemplate, b.varType(variable, "number"), false)+"}}")
+				}
+				i = end
+				continue
+			}
+		}
+		c := text[i]
+		switch {
+		case inString && c == '\\' && i+1 < len(text):
+			out.WriteString(text[i : i+2])
+			i += 2
+			continue
+		case c == '"':
+			inString = !inString
+			stringStart = i
+		}
+		out.WriteByte(c)
+		i++
+	}
+	b.tool.RequestTemplate.Body = out.String()
+}
+
+// writeAction 写入模板动作。前面紧跟的 { 会和动作连成 {{{，改写成输出 { 的模板动作
+func writeAction(out *bytes.Buffer, action string) {
+	if bytes.HasSuffix(out.Bytes(), []byte("{")) {
+		out.Truncate(out.Len() - 1)
+		out.WriteString(`{{"{"}}`)
+	}
+	out.WriteString(action)
+}
+
+// jsonFields 把 json 对象的每个字段变成 body 参数，类型取自字段的值
+func (b *toolBuilder) jsonFields(fields map[string]any) {
+	for _, name := range sortedKeys(fields) {
+		b.bodyArg(name, jsonschema.TypeOf(fields[name]))
+	}
+}
+
+// bodyArg 添加以字段名作为参数名的 body 参数。字段名不能作为参数名或与已有参数重名时跳过
+func (b *toolBuilder) bodyArg(name, typ string) *ArgConfig {
+	if argName(name) != name || b.tool.arg(name) != nil {
+		log.Printf("tool %s: body field %q can not be used as a parameter, skipped", b.tool.Name, name)
+		return nil
+	}
+	return b.tool.arg(b.addArg(name, positionBody, typ, false))
+}
+
+// formBody 把 urlencoded 表单转换成 body 模板，变量的值用 queryEscape 转义
+func (b *toolBuilder) formBody(pairs [][2]string) {
+	parts := make([]string, 0, len(pairs))
+	for _, p := range pairs {
+		var value strings.Builder
+		last := 0
+		for _, loc := range placeholderPattern.FindAllStringIndex(p[1], -1) {
+			value.WriteString(url.QueryEscape(p[1][last:loc[0]]))
+			value.WriteString(b.template(p[1][loc[0]:loc[1]], "queryEscape", true))
+			last = loc[1]
+		}
+		value.WriteString(url.QueryEscape(p[1][last:]))
+		if p[0] == "" {
+			// curl --data-urlencode 不带名称时只发送编码后的内容
+			parts = append(parts, value.String())
+			continue
+		}
+		parts = append(parts, url.QueryEscape(p[0])+"="+value.String())
+	}
+	b.tool.RequestTemplate.Body = strings.Join(parts, "&")
+	b.tool.RequestTemplate.ArgsToFormBody = true
+}
+
+// splitQuery 解析 a=1&b={{b}} 形式的 query，保留参数顺序和其中的变量
+func splitQuery(query string) [][2]string {
+	var pairs [][2]string
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		pairs = append(pairs, [2]string{key, value})
+	}
+	return pairs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath 是一个精简的 JSONPath 实现，用来从较大的响应体中挑出模型需要的字段。支持的语法：
+//
+//	$.data.items      字段，也可以写成 jq 风格的 .data.items
+//	$['a b']          带特殊字符的字段，多个字段用逗号分隔：$['id','name']
+//	$.items[0]        下标，负数从末尾开始
+//	$.items[1:3]      切片
+//	$.items[*].name   通配符，[] 与 [*] 相同
+//	$..name           递归查找
+//
+// 含通配符、切片、递归或多个字段的表达式总是返回数组
+type jsonPath struct {
+	expr  string
+	steps []pathStep
+	multi bool
+}
+
+type pathStep struct {
+	recursive bool
+	wildcard  bool
+	keys      []string
+	index     *int
+	slice     *[2]*int
+}
+
+func compileJSONPath(expr string) (*jsonPath, error) {
+	p := &jsonPath{expr: expr}
+	s := strings.TrimSpace(expr)
+	s = strings.TrimPrefix(s, "$")
+	if s != "" && s[0] != '.' && s[0] != '[' {
+		s = "." + s
+	}
+	for len(s) > 0 {
+		var step pathStep
+		switch {
+		case strings.HasPrefix(s, ".."):
+			step.recursive = true
+			s = s[2:]
+			if strings.HasPrefix(s, "[") {
+				var err error
+				if s, err = parseBracket(s, &step); err != nil {
+					return nil, fmt.Errorf("invalid jsonpath %q: %v", expr, err)
+				}
+				break
+			}
+			s = parseName(s, &step)
+		case strings.HasPrefix(s, "."):
+			s = parseName(s[1:], &step)
+		case strings.HasPrefix(s, "["):
+			var err error
+			if s, err = parseBracket(s, &step); err != nil {
+				return nil, fmt.Errorf("invalid jsonpath %q: %v", expr, err)
+			}
+		default:
+			return nil, fmt.Errorf("invalid jsonpath %q: unexpected %q", expr, s)
+		}
+		if !step.wildcard && step.keys == nil && step.index == nil && step.slice == nil {
+			if step.recursive {
+				return nil, fmt.Errorf("invalid jsonpath %q: .. must be followed by a field", expr)
+			}
+			// 单独的 . 表示当前节点
+			continue
+		}
+		if step.recursive || step.wildcard || step.slice != nil || len(step.keys) > 1 {
+			p.multi = true
+		}
+		p.steps = append(p.steps, step)
+	}
+	return p, nil
+}
+
+func parseName(s string, step *pathStep) string {
+	end := strings.IndexAny(s, ".[")
+	if end < 0 {
+		end = len(s)
+	}
+	name := s[:end]
+	switch name {
+	case "":
+	case "*":
+		step.wildcard = true
+	default:
+		step.keys = []string{name}
+	}
+	return s[end:]
+}
+
+func parseBracket(s string, step *pathStep) (string, error) {
+	end := -1
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			end = i
+		}
+		if end >= 0 {
+			break
+		}
+	}
+	if end < 0 {
+		return "", fmt.Errorf("unclosed [")
+	}
+	content, rest := strings.TrimSpace(s[1:end]), s[end+1:]
+	switch {
+	case content == "" || content == "*":
+		step.wildcard = true
+	case content[0] == '\'' || content[0] == '"':
+		for _, part := range strings.Split(content, ",") {
+			part = strings.TrimSpace(part)
+			if len(part) < 2 || part[0] != part[len(part)-1] {
+				return "", fmt.Errorf("invalid field %s", part)
+			}
+			step.keys = append(step.keys, part[1:len(part)-1])
+		}
+	case strings.Contains(content, ":"):
+		var bounds [2]*int
+		for i, part := range strings.SplitN(content, ":", 2) {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return "", fmt.Errorf("invalid slice %s", content)
+			}
+			bounds[i] = &n
+		}
+		step.slice = &bounds
+	default:
+		n, err := strconv.Atoi(content)
+		if err != nil {
+			return "", fmt.Errorf("invalid index %s", content)
+		}
+		step.index = &n
+	}
+	return rest, nil
+}
+
+// eval 对 json 解码后的数据求值，单值表达式找不到时返回 false
+func (p *jsonPath) eval(doc any) (any, bool) {
+	nodes := []any{doc}
+	for _, step := range p.steps {
+		var next []any
+		for _, node := range nodes {
+			if step.recursive {
+				for _, n := range descendants(node) {
+					next = append(next, step.apply(n)...)
+				}
+				continue
+			}
+			next = append(next, step.apply(node)...)
+		}
+		nodes = next
+	}
+	if p.multi {
+		if nodes == nil {
+			nodes = []any{}
+		}
+		return nodes, true
+	}
+	if len(nodes) == 0 {
+		return nil, false
+	}
+	return nodes[0], true
+}
+
+func (step pathStep) apply(node any) []any {
+	switch v := node.(type) {
+	case map[string]any:
+		if step.wildcard {
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			out := make([]any, 0, len(keys))
+			for _, k := range keys {
+				out = append(out, v[k])
+			}
+			return out
+		}
+		var out []any
+		for _, k := range step.keys {
+			if child, ok := v[k]; ok {
+				out = append(out, child)
+			}
+		}
+		return out
+	case []any:
+		switch {
+		case step.wildcard:
+			return v
+		case step.index != nil:
+			i := *step.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return nil
+			}
+			return []any{v[i]}
+		case step.slice != nil:
+			start, end := 0, len(v)
+			if step.slice[0] != nil {
+				start = clampIndex(*step.slice[0], len(v))
+			}
+			if step.slice[1] != nil {
+				end = clampIndex(*step.slice[1], len(v))
+			}
+			if start >= end {
+				return nil
+			}
+			return v[start:end]
+		}
+	}
+	return nil
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	return min(max(i, 0), n)
+}
+
+// descendants 返回节点本身及其所有子孙节点，用于 .. 递归查找
+func descendants(node any) []any {
+	out := []any{node}
+	switch v := node.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = append(out, descendants(v[k])...)
+		}
+	case []any:
+		for _, child := range v {
+			out = append(out, descendants(child)...)
+		}
+	}
+	return out
+}
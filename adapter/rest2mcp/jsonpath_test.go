@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	var doc any
+	json.Unmarshal([]byte(`{
+		"data": {
+			"total": 3,
+			"items": [
+				{"id": 1, "name": "a", "tags": ["x"]},
+				{"id": 2, "name": "b"},
+				{"id": 3, "name": "c", "owner": {"name": "d"}}
+			]
+		},
+		"odd key": true
+	}`), &doc)
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"$", ``},
+		{"$.data.total", `3`},
+		{".data.total", `3`},
+		{"data.total", `3`},
+		{"$['odd key']", `true`},
+		{"$.data.items[0].name", `"a"`},
+		{"$.data.items[-1].id", `3`},
+		{"$.data.items[*].id", `[1,2,3]`},
+		{".data.items[].name", `["a","b","c"]`},
+		{"$.data.items[1:].id", `[2,3]`},
+		{"$.data.items[:-2].id", `[1]`},
+		{"$.data.items[0]['id','name']", `[1,"a"]`},
+		{"$..name", `["a","b","c","d"]`},
+		{"$.data.items[*].tags[0]", `["x"]`},
+		{"$.data.missing[*]", `[]`},
+	}
+	for _, tt := range tests {
+		p, err := compileJSONPath(tt.expr)
+		if err != nil {
+			t.Errorf("%s: failed to compile: %v", tt.expr, err)
+			continue
+		}
+		got, ok := p.eval(doc)
+		if !ok {
+			t.Errorf("%s: matched nothing", tt.expr)
+			continue
+		}
+		if tt.want == "" {
+			continue
+		}
+		b, _ := json.Marshal(got)
+		if string(b) != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.expr, tt.want, b)
+		}
+	}
+
+	p, _ := compileJSONPath("$.data.items[5]")
+	if _, ok := p.eval(doc); ok {
+		t.Error("expected out of range index to match nothing")
+	}
+
+	for _, expr := range []string{"$.items[", "$.items[a]", "$.."} {
+		if _, err := compileJSONPath(expr); err == nil {
+			t.Errorf("%s: expected compile error", expr)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"text/template"
+
+	"mcp-demo/internal/jsonschema"
+)
+
+// FileConfig 描述 multipart 请求中的一个文件。
+// 参数的值可以是 base64 编码的文件内容，也可以是 adapter 中声明的 resource uri（如 docs://42），此时读取该 resource 的内容上传
+type FileConfig struct {
+	// Filename 是 go template，可以引用其他参数，如 "{{.args.name}}.pdf"；为空或引用的参数缺失时使用参数名
+	Filename string `json:"filename"`
+	// ContentType 为空时使用 resource 的 mimeType，再按内容推断
+	ContentType string `json:"contentType"`
+}
+
+func parseFilenameTemplates(toolName string, args []ArgConfig) (map[string]*template.Template, error) {
+	filenames := map[string]*template.Template{}
+	for _, arg := range args {
+		if arg.File == nil || arg.File.Filename == "" {
+			continue
+		}
+		tmpl, err := parseTemplate(toolName+".file."+arg.Name, arg.File.Filename, true)
+		if err != nil {
+			return nil, err
+		}
+		filenames[arg.Name] = tmpl
+	}
+	return filenames, nil
+}
+
+// isResourceURI 区分 resource uri 和 base64 内容，base64 中不会出现 ://
+func isResourceURI(s string) bool {
+	return strings.Contains(s, "://")
+}
+
+// checkFileArg 校验文件参数的值，供 validateArgs 调用
+func checkFileArg(v any) string {
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Sprintf("expected base64 content or a resource uri, got %s", jsonschema.TypeOf(v))
+	}
+	if !isResourceURI(s) {
+		if _, err := base64.StdEncoding.DecodeString(s); err != nil {
+			return "must be base64 encoded file content or a resource uri"
+		}
+	}
+	return ""
+}
+
+// multipartBody 按参数声明的顺序构造 multipart/form-data 请求体，文件参数作为文件上传，其余参数作为普通字段
+func (r *route) multipartBody(ctx context.Context, data map[string]any, fields map[string]any) ([]byte, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, arg := range r.Args {
+		value, ok := fields[arg.Name]
+		if !ok {
+			continue
+		}
+		if arg.File == nil {
+			for _, v := range flatten(value) {
+				if err := w.WriteField(arg.Name, stringify(v)); err != nil {
+					return nil, "", err
+				}
+			}
+			continue
+		}
+
+		content, contentType, err := r.fileContent(ctx, arg, value)
+		if err != nil {
+			return nil, "", err
+		}
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, arg.Name, r.filename(arg, data)))
+		h.Set("Content-Type", contentType)
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(content); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// fileContent 取出文件内容和类型：resource uri 通过 readResource 读取，否则按 base64 解码。
+// dry run 时不读取 resource，以 <resource uri> 作为占位的内容
+func (r *route) fileContent(ctx context.Context, arg ArgConfig, value any) ([]byte, string, error) {
+	s, _ := value.(string)
+	var content []byte
+	var contentType string
+	if isResourceURI(s) && isDryRun(ctx) {
+		content, contentType = []byte("<resource "+s+">"), "application/octet-stream"
+	} else if isResourceURI(s) {
+		if r.readResource == nil {
+			return nil, "", fmt.Errorf("parameter %s: can not resolve resource %s", arg.Name, s)
+		}
+		var err error
+		if content, contentType, err = r.readResource(ctx, s); err != nil {
+			return nil, "", fmt.Errorf("parameter %s: %v", arg.Name, err)
+		}
+	} else {
+		var err error
+		if content, err = base64.StdEncoding.DecodeString(s); err != nil {
+			return nil, "", fmt.Errorf("parameter %s: invalid base64 content: %v", arg.Name, err)
+		}
+	}
+	if arg.File.ContentType != "" {
+		contentType = arg.File.ContentType
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	return content, contentType, nil
+}
+
+func (r *route) filename(arg ArgConfig, data map[string]any) string {
+	if tmpl := r.filenames[arg.Name]; tmpl != nil {
+		if name, ok, err := renderOptional(tmpl, data); err == nil && ok && name != "" {
+			return name
+		}
+	}
+	return arg.Name
+}
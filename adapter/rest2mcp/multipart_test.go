@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestMultipartUpload(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/docs/7" {
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF-stored"))
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		parts := map[string]any{"title": r.MultipartForm.Value["title"], "tags": r.MultipartForm.Value["tags"]}
+		for name, files := range r.MultipartForm.File {
+			f, _ := files[0].Open()
+			content, _ := io.ReadAll(f)
+			parts[name] = map[string]string{
+				"filename":    files[0].Filename,
+				"contentType": files[0].Header.Get("Content-Type"),
+				"content":     string(content),
+			}
+		}
+		json.NewEncoder(w).Encode(parts)
+	}))
+	defer upstream.Close()
+
+	cfg, err := parseConfig([]byte(`
+upstreams:
+  docs:
+    baseURL: ` + upstream.URL + `
+resources:
+  - name: doc
+    uri: "docs://{id}"
+    requestTemplate: {upstream: docs, url: "/docs/{id}"}
+tools:
+  - name: upload
+    args:
+      - name: title
+        required: true
+      - name: tags
+        type: array
+      - name: document
+        required: true
+        file:
+          filename: "{{.args.title}}.pdf"
+          contentType: application/pdf
+      - name: attachment
+        file: {}
+    requestTemplate:
+      upstream: docs
+      url: /upload
+      method: POST
+      argsToMultipartBody: true
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	r := a.routes[0]
+
+	call := func(args map[string]any) *mcp.CallToolResult {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = args
+		result, err := r.handle(context.Background(), request)
+		if err != nil {
+			t.Fatalf("failed to call tool: %v", err)
+		}
+		return result
+	}
+
+	result := call(map[string]any{
+		"title":      "report",
+		"tags":       []any{"a", "b"},
+		"document":   base64.StdEncoding.EncodeToString([]byte("%PDF-inline")),
+		"attachment": "docs://7",
+	})
+	var parts map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &parts); err != nil {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	document, _ := parts["document"].(map[string]any)
+	if document["filename"] != "report.pdf" || document["contentType"] != "application/pdf" || document["content"] != "%PDF-inline" {
+		t.Errorf("unexpected document part %v", document)
+	}
+	// resource 的内容和 mimeType，未配置文件名时使用参数名
+	attachment, _ := parts["attachment"].(map[string]any)
+	if attachment["filename"] != "attachment" || attachment["contentType"] != "application/pdf" || attachment["content"] != "%PDF-stored" {
+		t.Errorf("unexpected attachment part %v", attachment)
+	}
+	if tags, _ := json.Marshal(parts["tags"]); string(tags) != `["a","b"]` {
+		t.Errorf("expected repeated tags fields, got %s", tags)
+	}
+
+	// 文件参数不合法时返回 tool 错误
+	result = call(map[string]any{"title": "x", "document": "not base64!"})
+	if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "document: must be base64") {
+		t.Errorf("expected validation error, got %+v", result)
+	}
+
+	if _, err := parseConfig([]byte(`tools: [{name: a, args: [{name: f, file: {}}], requestTemplate: {url: http://localhost, method: POST}}]`)); err == nil {
+		t.Error("expected error for file parameter without argsToMultipartBody")
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"mcp-demo/internal/config"
+)
+
+// openAPIMethods 按固定顺序遍历 path item 中的 operation，保证生成的 tool 顺序稳定
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+type openAPIDoc struct {
+	OpenAPI string `json:"openapi"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+type openAPIOperation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Description string              `json:"description"`
+	Parameters  []openAPIParameter  `json:"parameters"`
+	RequestBody *openAPIRequestBody `json:"requestBody"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description"`
+	Required    bool           `json:"required"`
+	Schema      map[string]any `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+	Content     map[string]struct {
+		Schema map[string]any `json:"schema"`
+	} `json:"content"`
+}
+
+// loadOpenAPITools 读取 OpenAPI 3 文档（json 或 yaml），为每个 operation 生成一个 ToolConfig。
+// 指定 upstream 时 url 为相对路径，地址和认证取自该上游；否则 baseURL 为空时使用文档中第一个 servers.url
+func loadOpenAPITools(path, baseURL, upstream string) ([]ToolConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read openapi file: %v", err)
+	}
+	return parseOpenAPI(data, baseURL, upstream)
+}
+
+func parseOpenAPI(data []byte, baseURL, upstream string) ([]ToolConfig, error) {
+	var raw map[string]any
+	if err := config.Decode(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi document: %v", err)
+	}
+	// 先把文档内的 $ref 展开，后面就可以直接按结构体解析
+	resolved, err := resolveRefs(raw, raw, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc openAPIDoc
+	if err := remarshal(resolved, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi document: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %q, only 3.x is supported", doc.OpenAPI)
+	}
+	if upstream == "" {
+		if baseURL == "" && len(doc.Servers) > 0 {
+			baseURL = doc.Servers[0].URL
+		}
+		if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+			return nil, fmt.Errorf("base url %q is not absolute, please set it explicitly", baseURL)
+		}
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var tools []ToolConfig
+	for _, p := range paths {
+		item := doc.Paths[p]
+		// path item 级别的参数对其下所有 operation 生效
+		var common []openAPIParameter
+		if rawParams, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(rawParams, &common); err != nil {
+				return nil, fmt.Errorf("invalid parameters of path %s: %v", p, err)
+			}
+		}
+		for _, method := range openAPIMethods {
+			rawOp, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op openAPIOperation
+			if err := json.Unmarshal(rawOp, &op); err != nil {
+				return nil, fmt.Errorf("invalid operation %s %s: %v", method, p, err)
+			}
+			tool := op.toolConfig(method, p, baseURL, common)
+			tool.RequestTemplate.Upstream = upstream
+			tools = append(tools, tool)
+		}
+	}
+	return tools, nil
+}
+
+func (op openAPIOperation) toolConfig(method, path, baseURL string, common []openAPIParameter) ToolConfig {
+	tool := ToolConfig{
+		Name:        op.OperationID,
+		Description: strings.TrimSpace(strings.Join(nonEmpty(op.Summary, op.Description), "\n\n")),
+		RequestTemplate: RequestTemplate{
+			URL:    baseURL + path,
+			Method: strings.ToUpper(method),
+		},
+	}
+	if tool.Name == "" {
+		tool.Name = operationName(method, path)
+	}
+
+	// operation 上的参数覆盖 path item 上同名同位置的参数
+	params := map[string]openAPIParameter{}
+	var order []string
+	for _, p := range append(append([]openAPIParameter{}, common...), op.Parameters...) {
+		key := p.In + ":" + p.Name
+		if _, ok := params[key]; !ok {
+			order = append(order, key)
+		}
+		params[key] = p
+	}
+	seen := map[string]bool{}
+	for _, key := range order {
+		p := params[key]
+		if p.In == "cookie" {
+			log.Printf("tool %s: cookie parameter %s is not supported, skipped", tool.Name, p.Name)
+			continue
+		}
+		tool.Args = append(tool.Args, schemaArg(p.Name, p.Description, p.Required || p.In == positionPath, p.In, p.Schema))
+		seen[p.Name] = true
+	}
+
+	if op.RequestBody == nil {
+		return tool
+	}
+	schema, mediaType, ok := requestBodySchema(op.RequestBody)
+	if !ok {
+		log.Printf("tool %s: request body without json or form content is not supported, skipped", tool.Name)
+		return tool
+	}
+	props, _ := schema["properties"].(map[string]any)
+	form := mediaType == "multipart/form-data" || mediaType == "application/x-www-form-urlencoded"
+	tool.RequestTemplate.ArgsToMultipartBody = mediaType == "multipart/form-data"
+	tool.RequestTemplate.ArgsToFormBody = mediaType == "application/x-www-form-urlencoded"
+	if form && len(props) == 0 {
+		log.Printf("tool %s: form request body without properties is not supported, skipped", tool.Name)
+		return tool
+	}
+	if schemaType(schema) != "object" || len(props) == 0 {
+		// 非对象类型的请求体整体作为一个参数
+		tool.Args = append(tool.Args, schemaArg("body", op.RequestBody.Description, op.RequestBody.Required, positionBody, schema))
+		tool.RequestTemplate.BodyArg = "body"
+		return tool
+	}
+
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]any); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if seen[name] {
+			log.Printf("tool %s: body field %s conflicts with a parameter of the same name, skipped", tool.Name, name)
+			continue
+		}
+		prop, _ := props[name].(map[string]any)
+		arg := schemaArg(name, "", op.RequestBody.Required && required[name], positionBody, prop)
+		// multipart 中 format 为 binary 的字段作为文件上传
+		if tool.RequestTemplate.ArgsToMultipartBody && prop["format"] == "binary" {
+			arg.File = &FileConfig{}
+			arg.Format = ""
+		}
+		tool.Args = append(tool.Args, arg)
+	}
+	return tool
+}
+
+// requestBodySchema 取请求体的 schema，优先使用 json，其次是 multipart 和 urlencoded 表单
+func requestBodySchema(body *openAPIRequestBody) (map[string]any, string, bool) {
+	if c, ok := body.Content["application/json"]; ok {
+		return c.Schema, "application/json", true
+	}
+	for mediaType, c := range body.Content {
+		if strings.HasSuffix(mediaType, "+json") {
+			return c.Schema, mediaType, true
+		}
+	}
+	for _, mediaType := range []string{"multipart/form-data", "application/x-www-form-urlencoded"} {
+		if c, ok := body.Content[mediaType]; ok {
+			return c.Schema, mediaType, true
+		}
+	}
+	return nil, "", false
+}
+
+// schemaArg 把参数的 json schema 转换成 ArgConfig，保留 enum、minimum、items 等约束
+func schemaArg(name, description string, required bool, position string, schema map[string]any) ArgConfig {
+	var arg ArgConfig
+	fields := make(map[string]any, len(schema))
+	for k, v := range schema {
+		fields[k] = v
+	}
+	// schema 中的 required 是对象的必填属性列表，与参数本身是否必填不同
+	if list, ok := fields["required"].([]any); ok {
+		for _, p := range list {
+			if s, ok := p.(string); ok {
+				arg.RequiredProperties = append(arg.RequiredProperties, s)
+			}
+		}
+	}
+	delete(fields, "required")
+	if err := remarshal(fields, &arg); err != nil {
+		log.Printf("parameter %s: unsupported schema, only the type is kept: %v", name, err)
+		arg = ArgConfig{}
+	}
+	arg.Name = name
+	arg.Type = schemaType(schema)
+	arg.Required = required
+	arg.Position = position
+	if description != "" {
+		arg.Description = description
+	}
+	return arg
+}
+
+func schemaType(schema map[string]any) string {
+	if t, ok := schema["type"].(string); ok {
+		return t
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return "string"
+}
+
+var nonNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// operationName 在没有 operationId 时用 method 和 path 拼出 tool 名称，如 get_users_id
+func operationName(method, path string) string {
+	name := nonNameChars.ReplaceAllString(method+"_"+path, "_")
+	return strings.Trim(strings.ReplaceAll(name, "__", "_"), "_")
+}
+
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// resolveRefs 递归展开文档内部的 $ref（仅支持 #/ 开头的本地引用）
+func resolveRefs(node any, root map[string]any, stack []string) (any, error) {
+	switch v := node.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			for _, r := range stack {
+				if r == ref {
+					// 循环引用无法展开成普通的 json schema，退化为不限制结构的对象
+					return map[string]any{"type": "object"}, nil
+				}
+			}
+			target, err := lookupRef(root, ref)
+			if err != nil {
+				return nil, err
+			}
+			return resolveRefs(target, root, append(stack, ref))
+		}
+		out := make(map[string]any, len(v))
+		for k, child := range v {
+			resolved, err := resolveRefs(child, root, stack)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, child := range v {
+			resolved, err := resolveRefs(child, root, stack)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func lookupRef(root map[string]any, ref string) (any, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q, only local references are supported", ref)
+	}
+	var cur any = root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return cur, nil
+}
+
+func remarshal(in any, out any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
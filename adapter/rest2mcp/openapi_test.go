@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const testOpenAPI = `
+openapi: 3.0.3
+info:
+  title: Orders
+  version: 1.0.0
+servers:
+  - url: http://orders.example.com/api
+paths:
+  /orders/{id}:
+    parameters:
+      - $ref: '#/components/parameters/OrderID'
+    get:
+      operationId: getOrder
+      summary: Get an order
+      description: Returns a single order.
+      parameters:
+        - name: expand
+          in: query
+          schema:
+            type: boolean
+        - name: fields
+          in: query
+          schema:
+            type: array
+            items:
+              type: string
+              enum: [items, payments]
+        - name: X-Tenant
+          in: header
+          required: true
+          schema:
+            type: string
+      responses:
+        200:
+          description: ok
+    put:
+      summary: Update an order
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Order'
+      responses:
+        200:
+          description: ok
+components:
+  parameters:
+    OrderID:
+      name: id
+      in: path
+      description: Order id
+      schema:
+        type: string
+  schemas:
+    Order:
+      type: object
+      required: [status]
+      properties:
+        status:
+          type: string
+          description: New status
+        amount:
+          type: number
+          minimum: 0
+`
+
+func TestParseOpenAPI(t *testing.T) {
+	tools, err := parseOpenAPI([]byte(testOpenAPI), "", "")
+	if err != nil {
+		t.Fatalf("failed to parse openapi: %v", err)
+	}
+	if len(tools) != 2 {
+		t.Fatalf("expected 2 tools, got %d", len(tools))
+	}
+
+	get := tools[0]
+	if get.Name != "getOrder" {
+		t.Errorf("expected tool name getOrder, got %q", get.Name)
+	}
+	if get.Description != "Get an order\n\nReturns a single order." {
+		t.Errorf("unexpected description %q", get.Description)
+	}
+	if get.RequestTemplate.URL != "http://orders.example.com/api/orders/{id}" || get.RequestTemplate.Method != "GET" {
+		t.Errorf("unexpected request template %+v", get.RequestTemplate)
+	}
+	wantArgs := []ArgConfig{
+		{Name: "id", Description: "Order id", Type: "string", Required: true, Position: "path"},
+		{Name: "expand", Type: "boolean", Position: "query"},
+		{Name: "fields", Type: "array", Position: "query", Items: map[string]any{"type": "string", "enum": []any{"items", "payments"}}},
+		{Name: "X-Tenant", Type: "string", Required: true, Position: "header"},
+	}
+	if len(get.Args) != len(wantArgs) {
+		t.Fatalf("expected %d args, got %+v", len(wantArgs), get.Args)
+	}
+	for i, want := range wantArgs {
+		if !reflect.DeepEqual(get.Args[i], want) {
+			t.Errorf("arg %d: expected %+v, got %+v", i, want, get.Args[i])
+		}
+	}
+
+	// 没有 operationId 时使用 method + path 生成名称，请求体字段展开成参数
+	put := tools[1]
+	if put.Name != "put_orders_id" {
+		t.Errorf("expected tool name put_orders_id, got %q", put.Name)
+	}
+	if len(put.Args) != 3 || put.Args[1].Name != "amount" || put.Args[2].Name != "status" || !put.Args[2].Required {
+		t.Errorf("unexpected body args %+v", put.Args)
+	}
+	if min := put.Args[1].Minimum; min == nil || *min != 0 || put.Args[2].Description != "New status" {
+		t.Errorf("expected schema constraints to be kept, got %+v", put.Args)
+	}
+}
+
+func TestOpenAPIToolHandler(t *testing.T) {
+	// 模拟上游 rest 服务
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"method": r.Method,
+			"path":   r.URL.EscapedPath(),
+			"query":  r.URL.RawQuery,
+			"tenant": r.Header.Get("X-Tenant"),
+			"body":   string(body),
+		})
+	}))
+	defer upstream.Close()
+
+	tools, err := parseOpenAPI([]byte(testOpenAPI), upstream.URL, "")
+	if err != nil {
+		t.Fatalf("failed to parse openapi: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		tool ToolConfig
+		args map[string]any
+		want map[string]string
+	}{
+		{
+			name: "path query header",
+			tool: tools[0],
+			args: map[string]any{"id": "a/1", "expand": true, "X-Tenant": "t1"},
+			want: map[string]string{"method": "GET", "path": "/orders/a%2F1", "query": "expand=true", "tenant": "t1", "body": ""},
+		},
+		{
+			name: "json body",
+			tool: tools[1],
+			args: map[string]any{"id": "42", "status": "paid", "amount": 9.5},
+			want: map[string]string{"method": "PUT", "path": "/orders/42", "query": "", "tenant": "", "body": `{"amount":9.5,"status":"paid"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Name = tt.tool.Name
+			request.Params.Arguments = tt.args
+
+			r, err := newRoute(tt.tool, nil)
+			if err != nil {
+				t.Fatalf("failed to create route: %v", err)
+			}
+			result, err := r.handle(context.Background(), request)
+			if err != nil {
+				t.Fatalf("failed to call tool: %v", err)
+			}
+			var got map[string]string
+			if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+				t.Fatalf("failed to decode result: %v", err)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s: expected %q, got %q", k, v, got[k])
+				}
+			}
+		})
+	}
+
+	t.Run("missing required", func(t *testing.T) {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{"id": "42"}
+		r, err := newRoute(tools[0], nil)
+		if err != nil {
+			t.Fatalf("failed to create route: %v", err)
+		}
+		result, err := r.handle(context.Background(), request)
+		if err != nil {
+			t.Fatalf("expected tool error, got protocol error: %v", err)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "X-Tenant: is required") {
+			t.Errorf("expected tool error for missing X-Tenant, got %+v", result)
+		}
+	})
+}
+
+func TestOpenAPIMultipart(t *testing.T) {
+	tools, err := parseOpenAPI([]byte(`
+openapi: 3.0.3
+paths:
+  /avatars:
+    post:
+      operationId: uploadAvatar
+      requestBody:
+        content:
+          multipart/form-data:
+            schema:
+              type: object
+              properties:
+                userId: {type: string}
+                image: {type: string, format: binary}
+`), "http://localhost", "")
+	if err != nil {
+		t.Fatalf("failed to parse openapi: %v", err)
+	}
+	tool := tools[0]
+	if !tool.RequestTemplate.ArgsToMultipartBody || len(tool.Args) != 2 {
+		t.Fatalf("unexpected multipart tool %+v", tool)
+	}
+	if tool.Args[0].Name != "image" || tool.Args[0].File == nil || tool.Args[1].File != nil {
+		t.Errorf("expected image to be a file part, got %+v", tool.Args)
+	}
+	if _, err := newRoute(tool, nil); err != nil {
+		t.Errorf("failed to create route: %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// 翻页方式
+const (
+	pageByLink   = "link"
+	pageByCursor = "cursor"
+	pageByPage   = "page"
+	pageByOffset = "offset"
+)
+
+// PaginationConfig 描述列表接口的翻页方式。默认由 adapter 连续请求多页并把条目合并成一个结果，
+// exposeCursor 为 true 时改为给 tool 增加 cursor 参数，由模型自己翻页
+type PaginationConfig struct {
+	// Mode 为 link（Link 响应头中的 rel=next）、cursor（响应体中的游标）、page（页码）或 offset（偏移量）
+	Mode string `json:"mode"`
+	// Items 是 JSONPath，取每页中的条目数组，为空时整个响应体就是数组
+	Items string `json:"items"`
+	// NextCursor 是 JSONPath，cursor 模式下从响应体中取下一页的游标，为空表示没有下一页
+	NextCursor string `json:"nextCursor"`
+	// Param 是 cursor、page、offset 模式下携带游标、页码或偏移量的 query 参数
+	Param string `json:"param"`
+	// Start 是 page 模式的第一页页码，默认 1
+	Start *int `json:"start"`
+	// PageSize 是每页的条目数，page、offset 模式下返回的条目少于它时认为已经是最后一页
+	PageSize int `json:"pageSize"`
+	// MaxPages 和 MaxItems 限制自动翻页时最多请求的页数和返回的条目数，MaxPages 默认 5
+	MaxPages int `json:"maxPages"`
+	MaxItems int `json:"maxItems"`
+	// ExposeCursor 为 true 时不自动翻页，结果中给出下一页的 cursor，由模型通过 cursor 参数继续请求
+	ExposeCursor bool `json:"exposeCursor"`
+}
+
+// cursorArg 是 exposeCursor 时增加的参数
+const cursorArg = "cursor"
+
+type paginator struct {
+	PaginationConfig
+	items      *jsonPath
+	nextCursor *jsonPath
+}
+
+func newPaginator(t ToolConfig) (*paginator, error) {
+	if t.Pagination == nil {
+		return nil, nil
+	}
+	p := &paginator{PaginationConfig: *t.Pagination}
+	switch p.Mode {
+	case pageByLink:
+	case pageByCursor:
+		if p.NextCursor == "" || p.Param == "" {
+			return nil, fmt.Errorf("tool %s: pagination.nextCursor and pagination.param are required for cursor mode", t.Name)
+		}
+	case pageByPage, pageByOffset:
+		if p.Param == "" {
+			return nil, fmt.Errorf("tool %s: pagination.param is required for %s mode", t.Name, p.Mode)
+		}
+	default:
+		return nil, fmt.Errorf("tool %s: unsupported pagination mode %q", t.Name, p.Mode)
+	}
+	if p.MaxPages <= 0 {
+		p.MaxPages = 5
+	}
+	var err error
+	if p.Items != "" {
+		if p.items, err = compileJSONPath(p.Items); err != nil {
+			return nil, fmt.Errorf("tool %s: pagination.items: %v", t.Name, err)
+		}
+	}
+	if p.NextCursor != "" {
+		if p.nextCursor, err = compileJSONPath(p.NextCursor); err != nil {
+			return nil, fmt.Errorf("tool %s: pagination.nextCursor: %v", t.Name, err)
+		}
+	}
+	for _, arg := range t.Args {
+		if p.ExposeCursor && arg.Name == cursorArg {
+			return nil, fmt.Errorf("tool %s: parameter %s conflicts with the pagination cursor", t.Name, cursorArg)
+		}
+	}
+	return p, nil
+}
+
+// pageRequest 是翻页时对请求的修改：link 模式直接请求下一页的地址，其余模式覆盖 query 中的参数
+type pageRequest struct {
+	url   string
+	query url.Values
+}
+
+type pageRequestKey struct{}
+
+func withPageRequest(ctx context.Context, p *pageRequest) context.Context {
+	return context.WithValue(ctx, pageRequestKey{}, p)
+}
+
+func pageRequestFrom(ctx context.Context) *pageRequest {
+	p, _ := ctx.Value(pageRequestKey{}).(*pageRequest)
+	return p
+}
+
+// initial 返回第一次请求的游标：模型传入的 cursor，或 page、offset 模式下的起始值（参数中已经给出时以参数为准）
+func (p *paginator) initial(args map[string]any) string {
+	if cursor, _ := args[cursorArg].(string); p.ExposeCursor && cursor != "" {
+		return cursor
+	}
+	if p.Mode != pageByPage && p.Mode != pageByOffset {
+		return ""
+	}
+	if v, ok := args[p.Param]; ok && v != nil {
+		return stringify(v)
+	}
+	return strconv.Itoa(p.first())
+}
+
+// request 把游标转换成请求的修改
+func (p *paginator) request(cursor string) *pageRequest {
+	if p.Mode == pageByLink {
+		return &pageRequest{url: cursor}
+	}
+	return &pageRequest{query: url.Values{p.Param: {cursor}}}
+}
+
+// page 是解析好的一页
+type page struct {
+	items []any
+	next  string
+}
+
+// parse 从响应中取出条目和下一页的游标，current 是本页的游标
+func (p *paginator) parse(resp *upstreamResponse, current string) (*page, error) {
+	var data any
+	if err := json.Unmarshal(resp.body, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode response body: %v", err)
+	}
+	pg := &page{}
+	items := data
+	if p.items != nil {
+		items, _ = p.items.eval(data)
+	}
+	switch v := items.(type) {
+	case []any:
+		pg.items = v
+	case nil:
+	default:
+		return nil, fmt.Errorf("pagination items %s is not an array", p.Items)
+	}
+
+	switch p.Mode {
+	case pageByLink:
+		pg.next = nextLink(resp)
+	case pageByCursor:
+		if v, ok := p.nextCursor.eval(data); ok && v != nil && v != false && v != "" {
+			pg.next = stringify(v)
+		}
+	case pageByPage, pageByOffset:
+		if len(pg.items) == 0 || p.PageSize > 0 && len(pg.items) < p.PageSize {
+			break
+		}
+		n, err := strconv.Atoi(current)
+		if err != nil {
+			n = p.first()
+		}
+		if p.Mode == pageByPage {
+			pg.next = strconv.Itoa(n + 1)
+		} else {
+			pg.next = strconv.Itoa(n + len(pg.items))
+		}
+	}
+	return pg, nil
+}
+
+func (p *paginator) first() int {
+	if p.Mode == pageByPage {
+		if p.Start != nil {
+			return *p.Start
+		}
+		return 1
+	}
+	return 0
+}
+
+var linkNextPattern = regexp.MustCompile(`<([^>]*)>\s*;[^,]*\brel="?next"?`)
+
+// nextLink 取 Link 响应头中 rel=next 的地址，相对地址按本页地址解析
+func nextLink(resp *upstreamResponse) string {
+	for _, link := range resp.header.Values("Link") {
+		m := linkNextPattern.FindStringSubmatch(link)
+		if m == nil {
+			continue
+		}
+		base, err := url.Parse(resp.url)
+		ref, refErr := url.Parse(m[1])
+		if err != nil || refErr != nil {
+			return m[1]
+		}
+		return base.ResolveReference(ref).String()
+	}
+	return ""
+}
+
+// paginate 在拿到第一页后继续翻页并合并条目；exposeCursor 时只返回本页并附上下一页的 cursor。
+// 第二页起请求失败时返回已经取到的条目，并注明结果不完整
+func (r *route) paginate(ctx context.Context, args map[string]any, first *upstreamResponse, cursor string) (*mcp.CallToolResult, error) {
+	p := r.pagination
+	pg, err := p.parse(first, cursor)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if p.ExposeCursor {
+		result, err := r.buildResult(first)
+		if err != nil || pg.next == "" {
+			return result, err
+		}
+		result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf("more results are available, call %s again with %s=%q", r.Name, cursorArg, pg.next)))
+		return result, nil
+	}
+
+	// 一次调用只在第一页消耗限流令牌，后续的页只受并发数限制
+	ctx = withRateCharged(ctx)
+	items := pg.items
+	var failed error
+	pages := 1
+	for ; pg.next != "" && pages < p.MaxPages && (p.MaxItems <= 0 || len(items) < p.MaxItems); pages++ {
+		if pg, err = r.fetchPage(ctx, args, pg.next); err != nil {
+			failed = err
+			break
+		}
+		items = append(items, pg.items...)
+	}
+	if p.MaxItems > 0 && len(items) > p.MaxItems {
+		items = items[:p.MaxItems]
+	}
+
+	// 合并后的条目数组作为响应体，再按 responseTemplate 转换
+	body, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merged items: %v", err)
+	}
+	result, err := r.buildResult(&upstreamResponse{status: http.StatusOK, header: http.Header{"Content-Type": {"application/json"}}, body: body, url: first.url})
+	if err != nil || failed == nil {
+		return result, err
+	}
+	result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf("results are truncated after page %d, failed to fetch page %d: %v", pages, pages+1, failed)))
+	return result, nil
+}
+
+// fetchPage 请求并解析 cursor 对应的一页，上游的非 2xx 响应也作为错误返回
+func (r *route) fetchPage(ctx context.Context, args map[string]any, cursor string) (*page, error) {
+	resp, err := r.send(withPageRequest(ctx, r.pagination.request(cursor)), args)
+	if err != nil {
+		return nil, err
+	}
+	if resp.status < 200 || resp.status > 299 {
+		return nil, fmt.Errorf("upstream error: %d %s", resp.status, http.StatusText(resp.status))
+	}
+	return r.pagination.parse(resp, cursor)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// newListServer 提供 5 条数据、每页 2 条的列表接口，分别用四种方式翻页
+func newListServer(t *testing.T) *httptest.Server {
+	all := []int{1, 2, 3, 4, 5}
+	slice := func(offset int) []int {
+		if offset >= len(all) {
+			return []int{}
+		}
+		return all[offset:min(offset+2, len(all))]
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/link":
+			page, _ := strconv.Atoi(q.Get("p"))
+			if (page+1)*2 < len(all) {
+				w.Header().Set("Link", fmt.Sprintf(`</link?p=%d>; rel="next", </link?p=2>; rel="last"`, page+1))
+			}
+			json.NewEncoder(w).Encode(slice(page * 2))
+		case "/cursor":
+			offset, _ := strconv.Atoi(strings.TrimPrefix(q.Get("after"), "c"))
+			next := ""
+			if offset+2 < len(all) {
+				next = fmt.Sprintf("c%d", offset+2)
+			}
+			json.NewEncoder(w).Encode(map[string]any{"data": slice(offset), "next": next, "status": q.Get("status")})
+		case "/page":
+			page, _ := strconv.Atoi(q.Get("page"))
+			json.NewEncoder(w).Encode(slice((page - 1) * 2))
+		case "/offset":
+			offset, _ := strconv.Atoi(q.Get("offset"))
+			json.NewEncoder(w).Encode(map[string]any{"items": slice(offset)})
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestPagination(t *testing.T) {
+	upstream := newListServer(t)
+	cfg, err := parseConfig([]byte(`
+upstreams:
+  svc:
+    baseURL: ` + upstream.URL + `
+tools:
+  - name: by_link
+    requestTemplate: {upstream: svc, url: /link}
+    pagination: {mode: link}
+  - name: by_cursor
+    args: [{name: status, position: query}]
+    requestTemplate: {upstream: svc, url: /cursor}
+    pagination: {mode: cursor, items: $.data, nextCursor: $.next, param: after}
+  - name: by_page
+    requestTemplate: {upstream: svc, url: /page}
+    pagination: {mode: page, param: page, pageSize: 2, maxItems: 3}
+  - name: by_offset
+    requestTemplate: {upstream: svc, url: /offset}
+    responseTemplate: {body: "{{len .}} items"}
+    pagination: {mode: offset, items: $.items, param: offset, maxPages: 2}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	want := map[string]string{
+		"by_link":   "[1,2,3,4,5]",
+		"by_cursor": "[1,2,3,4,5]",
+		"by_page":   "[1,2,3]",
+		"by_offset": "4 items",
+	}
+	for _, r := range a.routes {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{"status": "paid"}
+		result, err := r.handle(context.Background(), request)
+		if err != nil {
+			t.Fatalf("%s: failed to call tool: %v", r.Name, err)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; text != want[r.Name] {
+			t.Errorf("%s: got %q, want %q", r.Name, text, want[r.Name])
+		}
+	}
+
+	invalid := map[string]string{
+		"unknown mode":   `{mode: scroll}`,
+		"missing param":  `{mode: page}`,
+		"missing cursor": `{mode: cursor, param: after}`,
+	}
+	for name, p := range invalid {
+		if _, err := parseConfig([]byte(`tools: [{name: a, requestTemplate: {url: http://localhost}, pagination: ` + p + `}]`)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestPaginationExposeCursor(t *testing.T) {
+	upstream := newListServer(t)
+	cfg, err := parseConfig([]byte(`
+upstreams:
+  svc:
+    baseURL: ` + upstream.URL + `
+tools:
+  - name: by_link
+    requestTemplate: {upstream: svc, url: /link}
+    pagination: {mode: link, exposeCursor: true}
+  - name: by_cursor
+    args: [{name: status, position: query}]
+    requestTemplate: {upstream: svc, url: /cursor}
+    responseTemplate: {select: $.data}
+    pagination: {mode: cursor, items: $.data, nextCursor: $.next, param: after, exposeCursor: true}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	if _, ok := a.routes[0].mcpTool().InputSchema.Properties[cursorArg]; !ok {
+		t.Error("expected cursor parameter in input schema")
+	}
+
+	cursorPattern := regexp.MustCompile(`cursor="([^"]+)"`)
+	for _, r := range a.routes {
+		var pages []string
+		cursor := ""
+		for i := 0; i < 5; i++ {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = map[string]any{"status": "paid"}
+			if cursor != "" {
+				request.Params.Arguments.(map[string]any)[cursorArg] = cursor
+			}
+			result, err := r.handle(context.Background(), request)
+			if err != nil {
+				t.Fatalf("%s: failed to call tool: %v", r.Name, err)
+			}
+			pages = append(pages, strings.TrimSpace(result.Content[0].(mcp.TextContent).Text))
+			if len(result.Content) == 1 {
+				break
+			}
+			m := cursorPattern.FindStringSubmatch(result.Content[1].(mcp.TextContent).Text)
+			if m == nil {
+				t.Fatalf("%s: no cursor in %+v", r.Name, result.Content[1])
+			}
+			cursor = m[1]
+		}
+		if got := strings.Join(pages, " "); got != "[1,2] [3,4] [5]" {
+			t.Errorf("%s: unexpected pages %s", r.Name, got)
+		}
+	}
+
+	// link 模式的 cursor 只能指向同一个上游
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{cursorArg: "http://evil.example.com/link?p=1"}
+	if _, err := a.routes[0].handle(context.Background(), request); err == nil || !strings.Contains(err.Error(), "invalid page cursor") {
+		t.Errorf("expected foreign cursor to be rejected, got %v", err)
+	}
+}
+
+func TestPaginationPartialFailure(t *testing.T) {
+	// 第二页起失败时返回已经取到的条目并注明结果不完整
+	var status atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 1 {
+			w.Write([]byte(`[1,2]`))
+			return
+		}
+		if code := int(status.Load()); code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+		w.Write([]byte(`not json`))
+	}))
+	defer upstream.Close()
+
+	cfg, err := parseConfig([]byte(`
+tools:
+  - name: list
+    requestTemplate: {url: ` + upstream.URL + `/list}
+    pagination: {mode: page, param: page, pageSize: 2}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusServiceUnavailable, "failed to fetch page 2: upstream error: 503 Service Unavailable"},
+		{http.StatusOK, "failed to fetch page 2: failed to decode response body"},
+	}
+	for _, tt := range tests {
+		status.Store(int32(tt.status))
+		result, err := a.routes[0].handle(context.Background(), mcp.CallToolRequest{})
+		if err != nil {
+			t.Fatalf("%d: unexpected error %v", tt.status, err)
+		}
+		if result.IsError || len(result.Content) != 2 {
+			t.Fatalf("%d: expected partial result, got %+v", tt.status, result)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; text != "[1,2]" {
+			t.Errorf("%d: expected items of the first page, got %q", tt.status, text)
+		}
+		if note := result.Content[1].(mcp.TextContent).Text; !strings.Contains(note, "truncated after page 1") || !strings.Contains(note, tt.want) {
+			t.Errorf("%d: unexpected note %q", tt.status, note)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// postmanCollection 是 Postman v2.1 集合中用到的部分，folder 和请求都是 item
+type postmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem `json:"item"`
+	Variable []postmanKV   `json:"variable"`
+}
+
+type postmanItem struct {
+	Name        string          `json:"name"`
+	Description postmanText     `json:"description"`
+	Item        []postmanItem   `json:"item"`
+	Request     *postmanRequest `json:"request"`
+}
+
+type postmanRequest struct {
+	Method      string       `json:"method"`
+	Header      []postmanKV  `json:"header"`
+	URL         postmanURL   `json:"url"`
+	Body        *postmanBody `json:"body"`
+	Description postmanText  `json:"description"`
+	Auth        *struct {
+		Type string `json:"type"`
+	} `json:"auth"`
+}
+
+type postmanURL struct {
+	Raw      string      `json:"raw"`
+	Query    []postmanKV `json:"query"`
+	Variable []postmanKV `json:"variable"`
+}
+
+// UnmarshalJSON 支持字符串形式的 url
+func (u *postmanURL) UnmarshalJSON(b []byte) error {
+	var raw string
+	if err := json.Unmarshal(b, &raw); err == nil {
+		*u = postmanURL{Raw: raw}
+		return nil
+	}
+	type plain postmanURL
+	return json.Unmarshal(b, (*plain)(u))
+}
+
+type postmanKV struct {
+	Key         string      `json:"key"`
+	Value       string      `json:"value"`
+	Type        string      `json:"type"`
+	Src         any         `json:"src"`
+	Disabled    bool        `json:"disabled"`
+	Description postmanText `json:"description"`
+}
+
+type postmanBody struct {
+	Mode       string      `json:"mode"`
+	Raw        string      `json:"raw"`
+	URLEncoded []postmanKV `json:"urlencoded"`
+	FormData   []postmanKV `json:"formdata"`
+	Options    struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+// postmanText 是说明文字，可以是字符串或 {"content": "..."}
+type postmanText string
+
+func (t *postmanText) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = postmanText(s)
+		return nil
+	}
+	var obj struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	*t = postmanText(obj.Content)
+	return nil
+}
+
+// loadPostmanTools 读取 Postman v2.1 集合，为每个请求生成一个 ToolConfig。
+// 集合变量用于替换地址开头的变量，其余 {{name}} 变量和 :id 形式的路径变量变成 tool 的入参
+func loadPostmanTools(path, baseURL, upstream string) ([]ToolConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read postman collection: %v", err)
+	}
+	return parsePostman(data, baseURL, upstream)
+}
+
+func parsePostman(data []byte, baseURL, upstream string) ([]ToolConfig, error) {
+	var c postmanCollection
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse postman collection: %v", err)
+	}
+	if !strings.Contains(c.Info.Schema, "/v2.1") && !strings.Contains(c.Info.Schema, "/v2.0") {
+		return nil, fmt.Errorf("unsupported postman collection schema %q, only v2.1 is supported", c.Info.Schema)
+	}
+	vars := map[string]string{}
+	for _, v := range c.Variable {
+		vars[v.Key] = v.Value
+	}
+
+	var tools []ToolConfig
+	used := map[string]bool{}
+	var walk func(items []postmanItem) error
+	walk = func(items []postmanItem) error {
+		for _, item := range items {
+			if item.Request == nil {
+				if err := walk(item.Item); err != nil {
+					return err
+				}
+				continue
+			}
+			tool, err := item.toolConfig(vars, baseURL, upstream)
+			if err != nil {
+				return err
+			}
+			tool.Name = uniqueName(tool.Name, used)
+			tools = append(tools, tool)
+		}
+		return nil
+	}
+	if err := walk(c.Item); err != nil {
+		return nil, err
+	}
+	return tools, nil
+}
+
+func (item postmanItem) toolConfig(vars map[string]string, baseURL, upstream string) (ToolConfig, error) {
+	req := item.Request
+	description := string(req.Description)
+	if description == "" {
+		description = string(item.Description)
+	}
+	if description == "" {
+		description = item.Name
+	}
+	b := newToolBuilder(item.Name, description, req.Method, vars)
+	// 路径变量的示例值和说明
+	for _, v := range req.URL.Variable {
+		if _, ok := b.vars[v.Key]; !ok && v.Value != "" {
+			b.vars[v.Key] = v.Value
+		}
+		b.descriptions[v.Key] = string(v.Description)
+	}
+	for _, q := range req.URL.Query {
+		if variable, ok := wholePlaceholder(q.Value); ok {
+			b.descriptions[variable] = string(q.Description)
+		}
+	}
+
+	if err := b.setURL(req.URL.Raw, baseURL, upstream); err != nil {
+		return ToolConfig{}, err
+	}
+	// 认证信息应当配置在上游上，不作为 tool 的入参
+	if req.Auth != nil && req.Auth.Type != "noauth" {
+		log.Printf("tool %s: postman auth %s is not imported, configure auth on the upstream instead", b.tool.Name, req.Auth.Type)
+	}
+	query := req.URL.Query
+	if _, rawQuery, ok := strings.Cut(req.URL.Raw, "?"); ok && len(query) == 0 {
+		for _, p := range splitQuery(rawQuery) {
+			query = append(query, postmanKV{Key: p[0], Value: p[1]})
+		}
+	}
+	for _, q := range query {
+		if !q.Disabled {
+			b.param(positionQuery, q.Key, q.Value)
+		}
+	}
+	for _, h := range req.Header {
+		if h.Disabled || strings.EqualFold(h.Key, "Content-Length") || strings.EqualFold(h.Key, "Host") {
+			continue
+		}
+		// Authorization、Cookie、X-Api-Key 等 header 中写死的凭证不写进配置，和 har 一样只保留引用了变量的
+		if secretName.MatchString(h.Key) && !placeholderPattern.MatchString(h.Value) {
+			log.Printf("tool %s: header %s is not imported, configure auth on the upstream instead", b.tool.Name, h.Key)
+			continue
+		}
+		b.param(positionHeader, h.Key, h.Value)
+	}
+
+	if req.Body == nil {
+		return b.tool, nil
+	}
+	switch req.Body.Mode {
+	case "", "none":
+	case "raw":
+		if req.Body.Options.Raw.Language == "json" || json.Valid([]byte(placeholderPattern.ReplaceAllString(req.Body.Raw, "0"))) {
+			b.jsonBody(req.Body.Raw)
+		} else {
+			b.tool.RequestTemplate.Body = b.template(req.Body.Raw, "", true)
+		}
+	case "urlencoded":
+		var pairs [][2]string
+		for _, p := range req.Body.URLEncoded {
+			if !p.Disabled {
+				pairs = append(pairs, [2]string{p.Key, p.Value})
+			}
+		}
+		b.formBody(pairs)
+	case "formdata":
+		// multipart 不支持模板，每个字段作为一个 body 参数，文件字段作为文件参数
+		b.tool.RequestTemplate.ArgsToMultipartBody = true
+		for _, p := range req.Body.FormData {
+			if p.Disabled {
+				continue
+			}
+			if arg := b.bodyArg(p.Key, "string"); arg != nil && p.Type == "file" {
+				arg.File = &FileConfig{}
+			}
+		}
+	default:
+		log.Printf("tool %s: postman body mode %s is not supported, skipped", b.tool.Name, req.Body.Mode)
+	}
+	return b.tool, nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const testPostman = `{
+  "info": {"name": "Users", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+  "variable": [{"key": "baseUrl", "value": "http://users.example.com/api"}, {"key": "age", "value": "30"}],
+  "item": [
+    {
+      "name": "Users",
+      "item": [
+        {
+          "name": "Get user",
+          "request": {
+            "method": "GET",
+            "header": [
+              {"key": "Authorization", "value": "Bearer {{token}}"},
+              {"key": "X-Debug", "value": "1", "disabled": true},
+              {"key": "X-Api-Key", "value": "s3cret"},
+              {"key": "Cookie", "value": "sid=abc"},
+              {"key": "Accept", "value": "application/json"}
+            ],
+            "url": {
+              "raw": "{{baseUrl}}/users/:id?expand={{expand}}&v=2",
+              "query": [{"key": "expand", "value": "{{expand}}", "description": "Related objects"}, {"key": "v", "value": "2"}],
+              "variable": [{"key": "id", "value": "42", "description": "User id"}]
+            },
+            "description": "Returns a user."
+          }
+        }
+      ]
+    },
+    {
+      "name": "Update user",
+      "request": {
+        "method": "PUT",
+        "url": "{{baseUrl}}/users/{{id}}",
+        "body": {
+          "mode": "raw",
+          "raw": "{\"name\": \"{{name}}\", \"age\": {{age}}, \"note\": \"hi {{name}}\", \"tags\": [{{tag}}]}",
+          "options": {"raw": {"language": "json"}}
+        }
+      }
+    },
+    {
+      "name": "Login",
+      "request": {
+        "method": "POST",
+        "url": "{{baseUrl}}/login",
+        "body": {"mode": "urlencoded", "urlencoded": [{"key": "user", "value": "{{user}}"}, {"key": "grant", "value": "pass word"}]}
+      }
+    },
+    {
+      "name": "Upload avatar",
+      "request": {
+        "method": "POST",
+        "url": "{{baseUrl}}/users/{{id}}/avatar",
+        "body": {"mode": "formdata", "formdata": [{"key": "file", "type": "file", "src": "/tmp/a.png"}, {"key": "caption", "type": "text", "value": ""}]}
+      }
+    },
+    {"name": "Get user", "request": {"method": "GET", "url": "{{baseUrl}}/me"}}
+  ]
+}`
+
+func TestParsePostman(t *testing.T) {
+	tools, err := parsePostman([]byte(testPostman), "", "")
+	if err != nil {
+		t.Fatalf("failed to parse postman collection: %v", err)
+	}
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	if want := []string{"get_user", "update_user", "login", "upload_avatar", "get_user_2"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("expected tools %v, got %v", want, names)
+	}
+
+	get := tools[0]
+	if get.Description != "Returns a user." || get.RequestTemplate.URL != "http://users.example.com/api/users/{id}" {
+		t.Errorf("unexpected tool %+v", get)
+	}
+	wantArgs := []ArgConfig{
+		{Name: "id", Description: "User id", Type: "integer", Required: true, Position: positionPath},
+		{Name: "expand", Description: "Related objects", Type: "string", Position: positionQuery},
+		{Name: "token", Type: "string", Position: positionTemplate},
+	}
+	if !reflect.DeepEqual(get.Args, wantArgs) {
+		t.Errorf("expected args %+v, got %+v", wantArgs, get.Args)
+	}
+	wantQuery := []ParamConfig{{Key: "v", Value: "2"}}
+	// 写死的凭证不会出现在生成的配置中
+	wantHeaders := []ParamConfig{{Key: "Authorization", Value: "Bearer {{.args.token}}"}, {Key: "Accept", Value: "application/json"}}
+	if !reflect.DeepEqual(get.RequestTemplate.Query, wantQuery) || !reflect.DeepEqual(get.RequestTemplate.Headers, wantHeaders) {
+		t.Errorf("unexpected query %+v or headers %+v", get.RequestTemplate.Query, get.RequestTemplate.Headers)
+	}
+
+	// json 请求体中的变量按位置转换，集合变量的值用来推断类型
+	update := tools[1]
+	wantBody := `{"name": {{json .args.name}}, "age": {{json .args.age}}, "note": "hi {{jsonEscape .args.name}}", "tags": [{{json .args.tag}}]}`
+	if update.RequestTemplate.Body != wantBody {
+		t.Errorf("unexpected body template %s", update.RequestTemplate.Body)
+	}
+	types := map[string]string{}
+	for _, arg := range update.Args {
+		types[arg.Name] = arg.Type
+	}
+	if want := map[string]string{"id": "string", "name": "string", "age": "integer", "tag": "number"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("expected arg types %v, got %v", want, types)
+	}
+
+	if login := tools[2]; login.RequestTemplate.Body != "user={{queryEscape .args.user}}&grant=pass+word" || !login.RequestTemplate.ArgsToFormBody {
+		t.Errorf("unexpected form body %+v", login.RequestTemplate)
+	}
+	if upload := tools[3]; !upload.RequestTemplate.ArgsToMultipartBody || upload.Args[1].File == nil || upload.Args[2].File != nil {
+		t.Errorf("unexpected multipart tool %+v", upload)
+	}
+
+	if _, err := parsePostman([]byte(`{"info": {"schema": "https://schema.getpostman.com/json/collection/v1.0.0/collection.json"}}`), "", ""); err == nil {
+		t.Error("expected error for unsupported schema")
+	}
+	if _, err := parsePostman([]byte(`{"info": {"schema": "v2.1.0"}, "item": [{"name": "a", "request": {"url": "{{host}}/a"}}]}`), "", ""); err == nil {
+		t.Error("expected error for unresolved base url")
+	}
+}
+
+func TestPostmanToolHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(map[string]string{
+			"path":  r.URL.Path,
+			"query": r.URL.RawQuery,
+			"auth":  r.Header.Get("Authorization"),
+			"type":  r.Header.Get("Content-Type"),
+			"body":  string(body),
+		})
+	}))
+	defer upstream.Close()
+
+	tools, err := parsePostman([]byte(testPostman), upstream.URL, "")
+	if err != nil {
+		t.Fatalf("failed to parse postman collection: %v", err)
+	}
+	a, err := newAdapter(&Config{Tools: tools})
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	tests := []struct {
+		route int
+		args  map[string]any
+		want  map[string]string
+	}{
+		{0, map[string]any{"id": 7.0, "token": "t1"}, map[string]string{"path": "/users/7", "query": "v=2", "auth": "Bearer t1"}},
+		{0, map[string]any{"id": 7.0, "expand": "roles"}, map[string]string{"query": "expand=roles&v=2", "auth": ""}},
+		{1, map[string]any{"id": "7", "name": `A "B"`, "age": 3.0, "tag": 1.0}, map[string]string{"body": `{"name": "A \"B\"", "age": 3, "note": "hi A \"B\"", "tags": [1]}`}},
+		{2, map[string]any{"user": "a&b"}, map[string]string{"type": "application/x-www-form-urlencoded", "body": "user=a%26b&grant=pass+word"}},
+	}
+	for _, tt := range tests {
+		r := a.routes[tt.route]
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = tt.args
+		result, err := r.handle(context.Background(), request)
+		if err != nil || result.IsError {
+			t.Fatalf("%s: unexpected result %v %+v", r.Name, err, result)
+		}
+		var got map[string]string
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+			t.Fatalf("%s: failed to decode result: %v", r.Name, err)
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("%s %s: expected %q, got %q", r.Name, k, v, got[k])
+			}
+		}
+	}
+}
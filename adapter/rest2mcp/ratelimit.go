@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig 限制发往上游的请求速率和并发数，可以配置在 upstream 或 tool 上，两者同时生效。
+// 每次发出的请求（包括重试）都会消耗一个令牌，自动翻页时只有第一页消耗令牌，命中缓存的调用不受限制
+type RateLimitConfig struct {
+	// Rate 是每秒的请求数，为 0 时不限制速率
+	Rate float64 `json:"rate"`
+	// Burst 是令牌桶的容量，默认为 Rate 向上取整
+	Burst int `json:"burst"`
+	// MaxInFlight 是同时进行的最大请求数，为 0 时不限制
+	MaxInFlight int `json:"maxInFlight"`
+	// Wait 为 true 时超出限制的请求排队，直到调用超时；否则立即返回 tool 错误
+	Wait bool `json:"wait"`
+}
+
+// rateLimitError 表示请求超出了限制，没有发出
+type rateLimitError struct {
+	scope    string
+	inFlight bool
+	retry    time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	retry := max(e.retry.Round(100*time.Millisecond), 100*time.Millisecond)
+	if e.inFlight {
+		return fmt.Sprintf("too many concurrent requests to %s, retry after %s", e.scope, retry)
+	}
+	return fmt.Sprintf("rate limit of %s exceeded, retry after %s", e.scope, retry)
+}
+
+type rateChargedKey struct{}
+
+// withRateCharged 标记这次调用已经消耗过令牌，之后的请求只受并发数限制
+func withRateCharged(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rateChargedKey{}, true)
+}
+
+func rateCharged(ctx context.Context) bool {
+	charged, _ := ctx.Value(rateChargedKey{}).(bool)
+	return charged
+}
+
+type limiter struct {
+	scope string
+	rate  float64
+	burst float64
+	wait  bool
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	// slots 的容量是 MaxInFlight，为 nil 时不限制并发
+	slots chan struct{}
+}
+
+func newLimiter(scope string, cfg *RateLimitConfig) (*limiter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Rate < 0 || cfg.Burst < 0 || cfg.MaxInFlight < 0 {
+		return nil, fmt.Errorf("%s: rateLimit values must not be negative", scope)
+	}
+	if cfg.Rate == 0 && cfg.MaxInFlight == 0 {
+		return nil, fmt.Errorf("%s: rateLimit requires rate or maxInFlight", scope)
+	}
+	l := &limiter{scope: scope, rate: cfg.Rate, burst: float64(cfg.Burst), wait: cfg.Wait, last: time.Now()}
+	if l.burst == 0 {
+		l.burst = math.Max(math.Ceil(cfg.Rate), 1)
+	}
+	l.tokens = l.burst
+	if cfg.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, cfg.MaxInFlight)
+	}
+	return l, nil
+}
+
+// acquire 取得令牌和并发名额，成功时返回的 release 必须在请求结束后调用
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	if !rateCharged(ctx) {
+		if err := l.take(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if l.slots == nil {
+		return func() {}, nil
+	}
+	release := func() { <-l.slots }
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if !l.wait {
+		return nil, &rateLimitError{scope: l.scope, inFlight: true, retry: time.Second}
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, &rateLimitError{scope: l.scope, inFlight: true, retry: time.Second}
+	}
+}
+
+// take 从令牌桶中取一个令牌。排队时先预留令牌再等待，等待的时间超过调用的截止时间时直接失败
+func (l *limiter) take(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		l.mu.Unlock()
+		return nil
+	}
+	wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	if deadline, ok := ctx.Deadline(); !l.wait || ok && now.Add(wait).After(deadline) {
+		l.mu.Unlock()
+		return &rateLimitError{scope: l.scope, retry: wait}
+	}
+	l.tokens--
+	l.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// 归还预留的令牌
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return &rateLimitError{scope: l.scope, retry: wait}
+	}
+}
+
+// limit 依次取得 tool 和上游的名额，返回的 release 释放两者
+func (r *route) limit(ctx context.Context) (func(), error) {
+	releaseRoute, err := r.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r.upstream == nil {
+		return releaseRoute, nil
+	}
+	releaseUpstream, err := r.upstream.limiter.acquire(ctx)
+	if err != nil {
+		releaseRoute()
+		return nil, err
+	}
+	return func() {
+		releaseUpstream()
+		releaseRoute()
+	}, nil
+}
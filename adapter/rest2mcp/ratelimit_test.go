@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRateLimit(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	cfg, err := parseConfig([]byte(`
+upstreams:
+  svc:
+    baseURL: ` + upstream.URL + `
+    rateLimit: {maxInFlight: 1}
+tools:
+  - name: reject
+    requestTemplate: {url: ` + upstream.URL + `/fast}
+    rateLimit: {rate: 1, burst: 2}
+  - name: queue
+    requestTemplate: {url: ` + upstream.URL + `/fast}
+    rateLimit: {rate: 20, burst: 1, wait: true}
+  - name: slow
+    requestTemplate: {upstream: svc, url: /slow}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	call := func(r *route) *mcp.CallToolResult {
+		result, err := r.handle(context.Background(), mcp.CallToolRequest{})
+		if err != nil {
+			t.Fatalf("%s: unexpected protocol error: %v", r.Name, err)
+		}
+		return result
+	}
+
+	// 令牌用完后不排队，直接返回带重试时间的 tool 错误
+	for i := 0; i < 2; i++ {
+		if result := call(a.routes[0]); result.IsError {
+			t.Fatalf("expected burst to be allowed: %+v", result)
+		}
+	}
+	result := call(a.routes[0])
+	if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "rate limit of tool reject exceeded, retry after") {
+		t.Errorf("unexpected result over the limit: %+v", result)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected limited call to skip upstream, got %d calls", n)
+	}
+
+	// 排队时按速率依次放行
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if result := call(a.routes[1]); result.IsError {
+			t.Fatalf("expected queued call to succeed: %+v", result)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected queued calls to be spaced out, took %s", elapsed)
+	}
+
+	// 上游的并发数用完时直接失败
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		call(a.routes[2])
+	}()
+	for a.upstreams["svc"].limiter != nil && len(a.upstreams["svc"].limiter.slots) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	result = call(a.routes[2])
+	if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "too many concurrent requests to upstream svc") {
+		t.Errorf("unexpected result over max in-flight: %+v", result)
+	}
+	close(release)
+	wg.Wait()
+	if result := call(a.routes[2]); result.IsError {
+		t.Errorf("expected slot to be released: %+v", result)
+	}
+}
+
+func TestRateLimitDeadline(t *testing.T) {
+	l, err := newLimiter("tool a", &RateLimitConfig{Rate: 1, Wait: true})
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	if err := l.take(context.Background()); err != nil {
+		t.Fatalf("expected first token: %v", err)
+	}
+	// 等待时间超过调用的截止时间时不排队
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.take(ctx); err == nil || time.Since(start) > 50*time.Millisecond {
+		t.Errorf("expected immediate rate limit error, got %v after %s", err, time.Since(start))
+	}
+
+	for name, cfg := range map[string]string{
+		"empty":    `{}`,
+		"negative": `{rate: -1}`,
+	} {
+		if _, err := parseConfig([]byte(`tools: [{name: a, requestTemplate: {url: http://localhost}, rateLimit: ` + cfg + `}]`)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRateLimitPagination(t *testing.T) {
+	// 自动翻页只在第一页消耗令牌，下一次调用才会被限流
+	upstream := newListServer(t)
+	cfg, err := parseConfig([]byte(`
+tools:
+  - name: list
+    requestTemplate: {url: ` + upstream.URL + `/page}
+    pagination: {mode: page, param: page, pageSize: 2}
+    rateLimit: {rate: 1}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	result, err := a.routes[0].handle(context.Background(), mcp.CallToolRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(result.Content) != 1 || result.Content[0].(mcp.TextContent).Text != "[1,2,3,4,5]" {
+		t.Errorf("expected all pages, got %+v", result.Content)
+	}
+	result, err = a.routes[0].handle(context.Background(), mcp.CallToolRequest{})
+	if err != nil || !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "rate limit of tool list exceeded") {
+		t.Errorf("expected the next call to be rate limited, got %v %+v", err, result)
+	}
+}
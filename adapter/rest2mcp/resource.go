@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yosida95/uritemplate/v3"
+)
+
+// ResourceConfig 把一个只读的 GET 接口声明成 mcp resource。
+// uri 中含有 {id} 这样的变量时注册为 resource template，变量按同名参数填到请求模板中
+type ResourceConfig struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// URI 如 orders://{id} 或 config://app
+	URI string `json:"uri"`
+	// MIMEType 为空时使用上游响应的 Content-Type
+	MIMEType        string          `json:"mimeType"`
+	RequestTemplate RequestTemplate `json:"requestTemplate"`
+	Timeout         Duration        `json:"timeout"`
+	Retry           RetryConfig     `json:"retry"`
+}
+
+// uriExprPattern 匹配 uri template 中的表达式，第一个分组是操作符
+var uriExprPattern = regexp.MustCompile(`\{([+#./;?&]?)([^}]*)\}`)
+
+type resource struct {
+	ResourceConfig
+	// template 为 nil 时是静态 resource
+	template *uritemplate.Template
+	route    *route
+}
+
+func (a *adapter) newResource(rc ResourceConfig, sc ServerConfig) (*resource, error) {
+	res := &resource{ResourceConfig: rc}
+	if strings.Contains(rc.URI, "{") {
+		tmpl, err := uritemplate.New(rc.URI)
+		if err != nil {
+			return nil, fmt.Errorf("resource %s: invalid uri template %s: %v", rc.Name, rc.URI, err)
+		}
+		res.template = tmpl
+	}
+	if rc.RequestTemplate.URL == "" {
+		return nil, fmt.Errorf("resource %s: requestTemplate.url is required", rc.Name)
+	}
+	if m := strings.ToUpper(rc.RequestTemplate.Method); m != "" && m != http.MethodGet {
+		return nil, fmt.Errorf("resource %s: only GET is supported, got %s", rc.Name, rc.RequestTemplate.Method)
+	}
+
+	// uri 中的变量作为参数：url 中引用了的填到 path，其余的作为 query；{?expand} 这样的查询变量是可选的
+	t := ToolConfig{
+		Name:            rc.Name,
+		Description:     rc.Description,
+		RequestTemplate: rc.RequestTemplate,
+		Timeout:         rc.Timeout,
+		Retry:           rc.Retry,
+	}
+	for _, m := range uriExprPattern.FindAllStringSubmatch(rc.URI, -1) {
+		optional := m[1] == "?" || m[1] == "&"
+		for _, name := range strings.Split(m[2], ",") {
+			name = strings.TrimSuffix(strings.SplitN(name, ":", 2)[0], "*")
+			arg := ArgConfig{Name: name, Type: "string", Required: !optional, Position: positionQuery}
+			if strings.Contains(rc.RequestTemplate.URL, "{"+name+"}") || strings.Contains(rc.RequestTemplate.URL, ".args."+name) {
+				arg.Position = positionPath
+			}
+			t.Args = append(t.Args, arg)
+		}
+	}
+	r, err := a.newRoute(t, sc)
+	if err != nil {
+		return nil, err
+	}
+	res.route = r
+	return res, nil
+}
+
+func (res *resource) register(s *server.MCPServer) {
+	if res.template == nil {
+		s.AddResource(mcp.NewResource(res.URI, res.Name,
+			mcp.WithResourceDescription(res.Description),
+			mcp.WithMIMEType(res.MIMEType),
+		), res.read)
+		return
+	}
+	s.AddResourceTemplate(mcp.NewResourceTemplate(res.URI, res.Name,
+		mcp.WithTemplateDescription(res.Description),
+		mcp.WithTemplateMIMEType(res.MIMEType),
+	), res.read)
+}
+
+// read 是 resources/read 的 handler，把上游响应体连同 content type 原样返回
+func (res *resource) read(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	ctx, cancel := context.WithTimeout(ctx, res.route.timeout)
+	defer cancel()
+
+	args := map[string]any{}
+	if res.template != nil {
+		// mcp-go 填入的 arguments 是 []string，这里按模板重新匹配取出单值
+		for name, v := range res.template.Match(request.Params.URI) {
+			switch {
+			case v.T == uritemplate.ValueTypeList:
+				args[name] = v.List()
+			case v.String() != "":
+				args[name] = v.String()
+			}
+		}
+	}
+	resp, err := res.route.send(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if resp.status < 200 || resp.status > 299 {
+		return nil, fmt.Errorf("upstream error: %d %s: %s", resp.status, http.StatusText(resp.status), truncate(string(resp.body), maxErrorBody))
+	}
+
+	mimeType := res.MIMEType
+	if mimeType == "" {
+		mimeType = resp.header.Get("Content-Type")
+	}
+	if isTextMIME(mimeType) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: request.Params.URI, MIMEType: mimeType, Text: string(resp.body)}}, nil
+	}
+	return []mcp.ResourceContents{mcp.BlobResourceContents{
+		URI:      request.Params.URI,
+		MIMEType: mimeType,
+		Blob:     base64.StdEncoding.EncodeToString(resp.body),
+	}}, nil
+}
+
+// readResource 按 uri 读取 adapter 中声明的 resource，返回内容和 mimeType
+func (a *adapter) readResource(ctx context.Context, uri string) ([]byte, string, error) {
+	for _, res := range a.resources {
+		if res.template == nil && res.URI != uri || res.template != nil && !res.template.Regexp().MatchString(uri) {
+			continue
+		}
+		request := mcp.ReadResourceRequest{}
+		request.Params.URI = uri
+		contents, err := res.read(ctx, request)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read resource %s: %v", uri, err)
+		}
+		switch c := contents[0].(type) {
+		case mcp.TextResourceContents:
+			return []byte(c.Text), c.MIMEType, nil
+		case mcp.BlobResourceContents:
+			data, err := base64.StdEncoding.DecodeString(c.Blob)
+			return data, c.MIMEType, err
+		}
+	}
+	return nil, "", fmt.Errorf("unknown resource %s", uri)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestResources(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"region":"cn"}`))
+		case "/orders/42":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"42","expand":"` + r.URL.Query().Get("expand") + `"}`))
+		case "/logo":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	cfg, err := parseConfig([]byte(`
+upstreams:
+  shop:
+    baseURL: ` + upstream.URL + `
+resources:
+  - name: config
+    uri: config://app
+    requestTemplate: {upstream: shop, url: /config}
+  - name: order
+    uri: "orders://{id}{?expand}"
+    requestTemplate: {upstream: shop, url: "/orders/{id}"}
+  - name: logo
+    uri: assets://logo
+    requestTemplate: {upstream: shop, url: /logo}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	s := server.NewMCPServer("test", "1.0.0")
+	a.register(s)
+
+	read := func(uri string) (mcp.JSONRPCMessage, []map[string]any) {
+		req, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0", "id": 1, "method": "resources/read",
+			"params": map[string]any{"uri": uri},
+		})
+		msg := s.HandleMessage(context.Background(), req)
+		resp, ok := msg.(mcp.JSONRPCResponse)
+		if !ok {
+			return msg, nil
+		}
+		b, _ := json.Marshal(resp.Result)
+		var result struct {
+			Contents []map[string]any `json:"contents"`
+		}
+		json.Unmarshal(b, &result)
+		return msg, result.Contents
+	}
+
+	_, contents := read("config://app")
+	if len(contents) != 1 || contents[0]["text"] != `{"region":"cn"}` || contents[0]["mimeType"] != "application/json" {
+		t.Errorf("unexpected static resource contents %v", contents)
+	}
+	_, contents = read("orders://42?expand=items")
+	if len(contents) != 1 || contents[0]["text"] != `{"id":"42","expand":"items"}` || contents[0]["uri"] != "orders://42?expand=items" {
+		t.Errorf("unexpected template resource contents %v", contents)
+	}
+	_, contents = read("orders://42")
+	if len(contents) != 1 || contents[0]["text"] != `{"id":"42","expand":""}` {
+		t.Errorf("expected optional query variable to be omitted, got %v", contents)
+	}
+	_, contents = read("assets://logo")
+	if len(contents) != 1 || contents[0]["blob"] != "iVBORw==" || contents[0]["mimeType"] != "image/png" {
+		t.Errorf("unexpected binary resource contents %v", contents)
+	}
+	msg, _ := read("orders://404")
+	if e, ok := msg.(mcp.JSONRPCError); !ok || !strings.Contains(e.Error.Message, "404") {
+		t.Errorf("expected upstream error, got %+v", msg)
+	}
+
+	invalid := map[string]string{
+		"post":          `resources: [{name: a, uri: "a://x", requestTemplate: {url: http://localhost, method: POST}}]`,
+		"missing uri":   `resources: [{name: a, requestTemplate: {url: http://localhost}}]`,
+		"bad template":  `resources: [{name: a, uri: "a://{id", requestTemplate: {url: http://localhost}}]`,
+		"duplicate uri": `resources: [{name: a, uri: "a://x", requestTemplate: {url: http://localhost}}, {name: b, uri: "a://x", requestTemplate: {url: http://localhost}}]`,
+	}
+	for name, data := range invalid {
+		if _, err := parseConfig([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultMaxResponseSize 是返回给模型的响应体的默认上限
+const defaultMaxResponseSize = 1 << 20
+
+// buildResult 把响应转换成 tool 结果：
+// 配置了 select 或 body 模板时按 json 处理，只保留选中的部分并渲染成给模型看的文本；
+// 否则按 Content-Type 返回图片、音频、文本或内嵌的二进制 resource。
+// 响应不是 json 或 select 没有选中内容时作为 tool 错误返回，模型可以看到上游实际返回了什么
+func (r *route) buildResult(resp *upstreamResponse) (*mcp.CallToolResult, error) {
+	body := resp.body
+	if r.selector == nil && r.response == nil {
+		return r.contentResult(resp), nil
+	}
+
+	// 解析响应体，如上游在 200 中返回的 html 错误页
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("upstream returned a non-json response (status %d, %s): %s",
+			resp.status, resp.header.Get("Content-Type"), truncate(string(body), 512))), nil
+	}
+
+	if r.selector != nil {
+		selected, ok := r.selector.eval(data)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("%s matched nothing in response: %s", r.selector.expr, truncate(string(body), 512))), nil
+		}
+		data = selected
+	}
+
+	if r.response == nil {
+		// 字符串直接返回，避免模型看到多余的引号
+		if s, ok := data.(string); ok {
+			return r.textResult(s), nil
+		}
+		out, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode selected value: %v", err)
+		}
+		return r.textResult(string(out)), nil
+	}
+
+	// 响应的结构与模板不符时同样作为 tool 错误
+	text, err := render(r.response, data)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return r.textResult(text), nil
+}
+
+// contentResult 按响应的 Content-Type 选择 tool 结果的内容类型
+func (r *route) contentResult(resp *upstreamResponse) *mcp.CallToolResult {
+	contentType := resp.header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if isTextMIME(contentType) {
+		return r.textResult(string(resp.body))
+	}
+	// 二进制内容截断后无法使用，超过上限时只说明情况
+	if len(resp.body) > r.maxSize {
+		return mcp.NewToolResultText(fmt.Sprintf("[omitted: %s response of %d bytes exceeds the max size of %d bytes]", mediaType, len(resp.body), r.maxSize))
+	}
+	data := base64.StdEncoding.EncodeToString(resp.body)
+	var content mcp.Content
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		content = mcp.NewImageContent(data, mediaType)
+	case strings.HasPrefix(mediaType, "audio/"):
+		content = mcp.NewAudioContent(data, mediaType)
+	default:
+		content = mcp.NewEmbeddedResource(mcp.BlobResourceContents{URI: resp.url, MIMEType: mediaType, Blob: data})
+	}
+	return &mcp.CallToolResult{Content: []mcp.Content{content}}
+}
+
+// textResult 返回文本结果，超过上限时截断并在末尾注明
+func (r *route) textResult(text string) *mcp.CallToolResult {
+	if len(text) <= r.maxSize {
+		return mcp.NewToolResultText(text)
+	}
+	cut := strings.ToValidUTF8(text[:r.maxSize], "")
+	return mcp.NewToolResultText(fmt.Sprintf("%s\n[truncated: showing the first %d of %d bytes]", cut, len(cut), len(text)))
+}
+
+// isTextMIME 判断响应能否以文本返回，未声明类型时按文本处理
+func isTextMIME(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json", strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/yaml", mediaType == "application/x-yaml",
+		mediaType == "application/csv", mediaType == "application/javascript":
+		return true
+	}
+	return false
+}
+
+// truncate 截断过长的文本，用于错误信息
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "...(truncated)"
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestBuildResult(t *testing.T) {
+	body := []byte(`{"code":0,"data":{"orders":[{"id":"o1","amount":12.5,"items":[1,2]},{"id":"o2","amount":3,"items":[]}],"debug":"..."}}`)
+
+	tests := []struct {
+		name     string
+		response ResponseTemplate
+		want     string
+	}{
+		{
+			name: "raw passthrough",
+			want: string(body),
+		},
+		{
+			name:     "select",
+			response: ResponseTemplate{Select: "$.data.orders[*].id"},
+			want:     `["o1","o2"]`,
+		},
+		{
+			name:     "select string",
+			response: ResponseTemplate{Select: "$.data.orders[0].id"},
+			want:     `o1`,
+		},
+		{
+			name:     "select and template",
+			response: ResponseTemplate{Select: "$.data.orders", Body: "{{range .}}{{.id}}: {{.amount}}\n{{end}}"},
+			want:     "o1: 12.5\no2: 3\n",
+		},
+		{
+			name:     "template with jsonpath",
+			response: ResponseTemplate{Body: `{{len (jsonpath . "$.data.orders")}} orders, ids {{json (jsonpath . "$..id")}}`},
+			want:     `2 orders, ids ["o1","o2"]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newRoute(ToolConfig{Name: "orders", RequestTemplate: RequestTemplate{URL: "http://localhost"}, ResponseTemplate: tt.response}, nil)
+			if err != nil {
+				t.Fatalf("failed to create route: %v", err)
+			}
+			result, err := r.buildResult(&upstreamResponse{body: body})
+			if err != nil {
+				t.Fatalf("failed to build result: %v", err)
+			}
+			if got := result.Content[0].(mcp.TextContent).Text; got != tt.want {
+				t.Errorf("unexpected result: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	r, _ := newRoute(ToolConfig{Name: "orders", RequestTemplate: RequestTemplate{URL: "http://localhost"}, ResponseTemplate: ResponseTemplate{Select: "$.data.total"}}, nil)
+	// 上游返回的内容不符合预期时作为 tool 错误交给模型，而不是协议错误
+	result, err := r.buildResult(&upstreamResponse{body: body})
+	if err != nil || !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "$.data.total matched nothing") {
+		t.Errorf("expected tool error when select matches nothing, got %v %v", result, err)
+	}
+	page := &upstreamResponse{status: http.StatusOK, header: http.Header{"Content-Type": {"text/html"}}, body: []byte("<html>maintenance</html>")}
+	result, err = r.buildResult(page)
+	if err != nil || !result.IsError || result.Content[0].(mcp.TextContent).Text != "upstream returned a non-json response (status 200, text/html): <html>maintenance</html>" {
+		t.Errorf("expected tool error for non json body, got %v %v", result, err)
+	}
+	if _, err := newRoute(ToolConfig{Name: "bad", RequestTemplate: RequestTemplate{URL: "http://localhost"}, ResponseTemplate: ResponseTemplate{Select: "$.a["}}, nil); err == nil {
+		t.Error("expected error for invalid select")
+	}
+}
+
+func TestContentResult(t *testing.T) {
+	r, err := newRoute(ToolConfig{Name: "files", RequestTemplate: RequestTemplate{URL: "http://localhost"}, ResponseTemplate: ResponseTemplate{MaxSize: 16}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create route: %v", err)
+	}
+	response := func(contentType string, body string) *upstreamResponse {
+		return &upstreamResponse{header: http.Header{"Content-Type": {contentType}}, body: []byte(body), url: "http://localhost/files/1"}
+	}
+
+	result, _ := r.buildResult(response("image/png", "\x89PNG"))
+	if img, ok := result.Content[0].(mcp.ImageContent); !ok || img.MIMEType != "image/png" || img.Data != "iVBORw==" {
+		t.Errorf("expected image content, got %+v", result.Content[0])
+	}
+	result, _ = r.buildResult(response("audio/mpeg", "ID3"))
+	if audio, ok := result.Content[0].(mcp.AudioContent); !ok || audio.MIMEType != "audio/mpeg" || audio.Data != "SUQz" {
+		t.Errorf("expected audio content, got %+v", result.Content[0])
+	}
+	result, _ = r.buildResult(response("text/csv; charset=utf-8", "id,amount\n1,2\n"))
+	if text, ok := result.Content[0].(mcp.TextContent); !ok || text.Text != "id,amount\n1,2\n" {
+		t.Errorf("expected csv as text, got %+v", result.Content[0])
+	}
+	result, _ = r.buildResult(response("application/pdf", "%PDF-1.7"))
+	res, ok := result.Content[0].(mcp.EmbeddedResource)
+	if !ok {
+		t.Fatalf("expected embedded resource, got %+v", result.Content[0])
+	}
+	if blob, ok := res.Resource.(mcp.BlobResourceContents); !ok || blob.MIMEType != "application/pdf" || blob.URI != "http://localhost/files/1" || blob.Blob != "JVBERi0xLjc=" {
+		t.Errorf("unexpected embedded resource %+v", res.Resource)
+	}
+
+	// 超过上限：文本截断并注明，二进制内容不返回
+	result, _ = r.buildResult(response("text/plain", "你好，这是一段很长的文本"))
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.HasPrefix(text, "你好，这是") || !strings.Contains(text, "[truncated: showing the first 15 of 36 bytes]") {
+		t.Errorf("unexpected truncated text %q", text)
+	}
+	result, _ = r.buildResult(response("image/png", strings.Repeat("x", 17)))
+	if text, ok := result.Content[0].(mcp.TextContent); !ok || !strings.Contains(text.Text, "exceeds the max size of 16 bytes") {
+		t.Errorf("expected oversized image to be omitted, got %+v", result.Content[0])
+	}
+}
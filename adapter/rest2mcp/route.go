@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-demo/internal/jsonschema"
+)
+
+// 参数位置，与 higress 的 args.position 保持一致
+const (
+	positionPath   = "path"
+	positionQuery  = "query"
+	positionHeader = "header"
+	positionBody   = "body"
+	// positionTemplate 的参数只在 query、headers、body 模板中引用，不会自动放到请求中
+	positionTemplate = "template"
+)
+
+// ToolConfig 描述一个 mcp tool 以及它对应的 rest 接口
+type ToolConfig struct {
+	Name             string           `json:"name"`
+	Description      string           `json:"description"`
+	Args             []ArgConfig      `json:"args"`
+	RequestTemplate  RequestTemplate  `json:"requestTemplate"`
+	ResponseTemplate ResponseTemplate `json:"responseTemplate"`
+	// Timeout 是一次调用（含重试）的超时时间，默认使用 server.client.timeout
+	Timeout Duration    `json:"timeout"`
+	Retry   RetryConfig `json:"retry"`
+	// Async 不为空时，上游返回 202 Accepted 后轮询 Location 直到任务结束，见 async.go
+	Async *AsyncConfig `json:"async"`
+	// Stream 不为空时，把上游的 sse 或 ndjson 流式响应逐块转发给客户端，见 stream.go
+	Stream *StreamConfig `json:"stream"`
+	// Pagination 不为空时按配置翻页，见 pagination.go
+	Pagination *PaginationConfig `json:"pagination"`
+	// Cache 不为空时缓存 GET、HEAD 请求的响应，见 cache.go
+	Cache *CacheConfig `json:"cache"`
+	// RateLimit 限制这个 tool 发往上游的请求，见 ratelimit.go
+	RateLimit *RateLimitConfig `json:"rateLimit"`
+	// GraphQL 不为空时把调用转换成 GraphQL 请求，入参作为 variables，见 graphql.go
+	GraphQL *GraphQLConfig `json:"graphql"`
+}
+
+// ArgConfig 描述 tool 的一个入参，Position 决定它被放到 http 请求的哪个位置。
+// Type 以及 Enum、Minimum 等约束与 json schema 中的同名关键字含义相同，调用前会按它们校验入参，见 schema.go
+type ArgConfig struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Position    string `json:"position"`
+
+	Enum      []any    `json:"enum,omitempty"`
+	Default   any      `json:"default,omitempty"`
+	Format    string   `json:"format,omitempty"`
+	Minimum   *float64 `json:"minimum,omitempty"`
+	Maximum   *float64 `json:"maximum,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+	MinItems  *int     `json:"minItems,omitempty"`
+	MaxItems  *int     `json:"maxItems,omitempty"`
+	// Items 是数组元素的 schema，Properties 是对象属性的 schema，写法同 json schema
+	Items      map[string]any `json:"items,omitempty"`
+	Properties map[string]any `json:"properties,omitempty"`
+	// RequiredProperties 是对象类型参数中必填的属性
+	RequiredProperties []string `json:"requiredProperties,omitempty"`
+
+	// File 不为空时参数作为 multipart 请求中的文件上传，见 multipart.go
+	File *FileConfig `json:"file,omitempty"`
+}
+
+// RequestTemplate 描述如何把 tool 调用转换成 http 请求，模板语法见 template.go
+type RequestTemplate struct {
+	// Upstream 引用 upstreams 中的上游，此时 URL 可以写成相对路径，并自动注入上游的认证信息
+	Upstream string        `json:"upstream"`
+	URL      string        `json:"url"`
+	Method   string        `json:"method"`
+	Query    []ParamConfig `json:"query"`
+	Headers  []ParamConfig `json:"headers"`
+	Body     string        `json:"body"`
+	// BodyArg 不为空时，该参数的值直接作为整个请求体，而不是拼成 json 对象
+	BodyArg string `json:"bodyArg"`
+	// 以下开关决定未声明 position 的参数放在哪里，默认放在 json 请求体中；
+	// argsToFormBody 和 argsToMultipartBody 同时决定 body 参数编码成 urlencoded 表单还是 multipart/form-data
+	ArgsToJsonBody      bool `json:"argsToJsonBody"`
+	ArgsToUrlParam      bool `json:"argsToUrlParam"`
+	ArgsToFormBody      bool `json:"argsToFormBody"`
+	ArgsToMultipartBody bool `json:"argsToMultipartBody"`
+}
+
+// ParamConfig 是一个 query 参数或 header，Value 为 go template
+type ParamConfig struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ResponseTemplate 描述如何把 http 响应转换成 tool 结果，都不配置时原样返回响应体
+type ResponseTemplate struct {
+	// Select 是 JSONPath 表达式，只返回 json 响应体中选中的部分，语法见 jsonpath.go
+	Select string `json:"select"`
+	// Body 是 go template，以 json 响应体（配置了 Select 时为选中的部分）作为数据渲染
+	Body string `json:"body"`
+	// MaxSize 是返回给模型的响应体上限（字节），默认使用 server.maxResponseSize，再默认 1MB；
+	// 文本超过上限时截断并注明，图片等二进制内容超过上限时不返回
+	MaxSize int `json:"maxSize"`
+	// Errors 把上游的非 2xx 状态码映射成给模型看的错误信息，key 为 404、4xx 或 default，value 为 go template
+	Errors map[string]string `json:"errors"`
+}
+
+// route 是解析好模板的 ToolConfig，负责一次 tool 调用的转发
+type route struct {
+	ToolConfig
+	upstream *upstream
+	client   *http.Client
+	timeout  time.Duration
+	maxSize  int
+	// breakers 为空或 breakerConfig 未启用时不熔断
+	breakers      *breakerSet
+	breakerConfig BreakerConfig
+	url           *template.Template
+	query         []*template.Template
+	headers       []*template.Template
+	body          *template.Template
+	selector      *jsonPath
+	response      *template.Template
+	errors        map[string]*template.Template
+	filenames     map[string]*template.Template
+	async         *asyncPoller
+	stream        *streamReader
+	pagination    *paginator
+	cache         *responseCache
+	limiter       *limiter
+	sessions      *sessionStore
+	graphql       *graphqlRequest
+	// dryRun 为 true 时不发送请求，返回构造好的请求
+	dryRun bool
+	// readResource 读取 adapter 自己的 resource，用于把 resource uri 作为文件上传
+	readResource func(ctx context.Context, uri string) ([]byte, string, error)
+}
+
+func newRoute(t ToolConfig, upstreams map[string]*upstream) (*route, error) {
+	r := &route{ToolConfig: t, client: http.DefaultClient, timeout: time.Duration(t.Timeout), maxSize: t.ResponseTemplate.MaxSize}
+	if r.timeout <= 0 {
+		r.timeout = defaultTimeout
+	}
+	if r.maxSize <= 0 {
+		r.maxSize = defaultMaxResponseSize
+	}
+	r.RequestTemplate.Method = strings.ToUpper(t.RequestTemplate.Method)
+	switch {
+	case r.RequestTemplate.Method != "":
+	case t.GraphQL != nil:
+		r.RequestTemplate.Method = http.MethodPost
+	default:
+		r.RequestTemplate.Method = http.MethodGet
+	}
+	rt := r.RequestTemplate
+	if rt.Upstream != "" {
+		if r.upstream = upstreams[rt.Upstream]; r.upstream == nil {
+			return nil, fmt.Errorf("tool %s: unknown upstream %s", t.Name, rt.Upstream)
+		}
+	}
+	modes := 0
+	for _, on := range []bool{rt.ArgsToJsonBody, rt.ArgsToUrlParam, rt.ArgsToFormBody, rt.ArgsToMultipartBody} {
+		if on {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return nil, fmt.Errorf("tool %s: only one of argsToJsonBody, argsToUrlParam, argsToFormBody and argsToMultipartBody can be set", t.Name)
+	}
+	if rt.ArgsToMultipartBody && (rt.Body != "" || rt.BodyArg != "") {
+		return nil, fmt.Errorf("tool %s: argsToMultipartBody can not be used with body or bodyArg", t.Name)
+	}
+	for _, arg := range t.Args {
+		if err := jsonschema.Check(arg.schema(), arg.Name); err != nil {
+			return nil, fmt.Errorf("tool %s: %v", t.Name, err)
+		}
+		if arg.File != nil && (!rt.ArgsToMultipartBody || r.positionOf(arg) != positionBody) {
+			return nil, fmt.Errorf("tool %s: file parameter %s must be a body parameter of an argsToMultipartBody request", t.Name, arg.Name)
+		}
+		if arg.Position == positionPath && !strings.Contains(rt.URL, "{"+arg.Name+"}") && !strings.Contains(rt.URL, ".args."+arg.Name) {
+			return nil, fmt.Errorf("tool %s: path parameter %s is not used in url %s", t.Name, arg.Name, rt.URL)
+		}
+	}
+	var err error
+	// url 中 {id} 形式的占位符会被替换成转义后的同名参数
+	if r.url, err = parseTemplate(t.Name+".url", expandPathParams(rt.URL), true); err != nil {
+		return nil, err
+	}
+	for _, q := range rt.Query {
+		tmpl, err := parseTemplate(t.Name+".query."+q.Key, q.Value, true)
+		if err != nil {
+			return nil, err
+		}
+		r.query = append(r.query, tmpl)
+	}
+	for _, h := range rt.Headers {
+		tmpl, err := parseTemplate(t.Name+".header."+h.Key, h.Value, true)
+		if err != nil {
+			return nil, err
+		}
+		r.headers = append(r.headers, tmpl)
+	}
+	if rt.Body != "" {
+		if r.body, err = parseTemplate(t.Name+".body", rt.Body, false); err != nil {
+			return nil, err
+		}
+	}
+	if t.ResponseTemplate.Select != "" {
+		if r.selector, err = compileJSONPath(t.ResponseTemplate.Select); err != nil {
+			return nil, fmt.Errorf("tool %s: %v", t.Name, err)
+		}
+	}
+	if t.ResponseTemplate.Body != "" {
+		if r.response, err = parseTemplate(t.Name+".response", t.ResponseTemplate.Body, false); err != nil {
+			return nil, err
+		}
+	}
+	if r.filenames, err = parseFilenameTemplates(t.Name, t.Args); err != nil {
+		return nil, err
+	}
+	if r.errors, err = parseErrorTemplates(t.Name, t.ResponseTemplate.Errors); err != nil {
+		return nil, err
+	}
+	if r.async, err = newAsyncPoller(t.Name, t.Async); err != nil {
+		return nil, err
+	}
+	if r.stream, err = newStreamReader(t); err != nil {
+		return nil, err
+	}
+	if r.pagination, err = newPaginator(t); err != nil {
+		return nil, err
+	}
+	if t.Cache != nil && r.RequestTemplate.Method != http.MethodGet && r.RequestTemplate.Method != http.MethodHead {
+		return nil, fmt.Errorf("tool %s: cache is only supported for GET and HEAD requests", t.Name)
+	}
+	r.cache = newResponseCache(t.Cache)
+	if r.limiter, err = newLimiter("tool "+t.Name, t.RateLimit); err != nil {
+		return nil, err
+	}
+	if r.graphql, err = newGraphQLRequest(r.ToolConfig); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// mcpTool 根据配置生成 mcp tool 定义，入参的 schema 由 ArgConfig.schema 生成
+func (t ToolConfig) mcpTool() mcp.Tool {
+	tool := mcp.NewTool(t.Name, mcp.WithDescription(t.Description))
+	tool.InputSchema.Properties = map[string]any{}
+	for _, arg := range t.Args {
+		tool.InputSchema.Properties[arg.Name] = arg.schema()
+		if arg.Required {
+			tool.InputSchema.Required = append(tool.InputSchema.Required, arg.Name)
+		}
+	}
+	if t.Pagination != nil && t.Pagination.ExposeCursor {
+		tool.InputSchema.Properties[cursorArg] = map[string]any{
+			"type":        "string",
+			"description": "Cursor of the page to fetch, taken from the previous result. Omit for the first page.",
+		}
+	}
+	return tool
+}
+
+// handle 是转发用的 tool handler：toolRequest -> httpRequest -> httpResponse -> toolResponse
+func (r *route) handle(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// 先补上默认值并按 schema 校验，不合法的调用不会发到上游
+	args := r.withDefaults(request.GetArguments())
+	if err := r.validateArgs(args); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if r.stream != nil {
+		callCtx = withChunkSink(callCtx, r.chunkSink(ctx, request))
+	}
+	// 翻页接口从第一页或模型传入的 cursor 开始
+	var cursor string
+	if r.pagination != nil {
+		if cursor = r.pagination.initial(args); cursor != "" {
+			callCtx = withPageRequest(callCtx, r.pagination.request(cursor))
+		}
+	}
+	if r.dryRun {
+		return r.explain(callCtx, args)
+	}
+	resp, err := r.send(callCtx, args)
+	if err != nil {
+		// 熔断或限流时快速失败，作为 tool 错误告诉模型上游暂不可用
+		var open *breakerOpenError
+		if errors.As(err, &open) {
+			return mcp.NewToolResultError(open.Error()), nil
+		}
+		var limited *rateLimitError
+		if errors.As(err, &limited) {
+			return mcp.NewToolResultError(limited.Error()), nil
+		}
+		var missing *missingCredentialsError
+		if errors.As(err, &missing) {
+			return mcp.NewToolResultError(missing.Error()), nil
+		}
+		return nil, err
+	}
+
+	// GraphQL 响应中的 errors 作为 tool 错误，没有错误时只把 data 交给后面的处理
+	if r.graphql != nil {
+		if resp, err = r.graphql.unwrap(resp); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	// 异步接口提交成功后轮询任务，轮询受 async.deadline 而不是 timeout 限制
+	if r.async != nil && resp.status == http.StatusAccepted {
+		return r.poll(ctx, request, resp, args)
+	}
+
+	// 上游返回的错误作为 tool 结果交给模型，只有网络等传输错误才作为协议错误返回
+	if resp.status < 200 || resp.status > 299 {
+		return r.errorResult(resp.status, resp.body, args)
+	}
+
+	if resp.streamed {
+		return r.textResult(string(resp.body)), nil
+	}
+	if r.pagination != nil {
+		return r.paginate(callCtx, args, resp, cursor)
+	}
+	return r.buildResult(resp)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"mcp-demo/internal/jsonschema"
+)
+
+// schema 生成参数的 json schema，未声明类型的参数按字符串处理
+func (arg ArgConfig) schema() map[string]any {
+	s := map[string]any{"type": arg.Type}
+	if arg.Type == "" {
+		s["type"] = "string"
+	}
+	if arg.Description != "" {
+		s["description"] = arg.Description
+	}
+	if arg.File != nil {
+		s["type"] = "string"
+		if arg.Description == "" {
+			s["description"] = "base64 encoded file content or a resource uri"
+		}
+	}
+	if len(arg.Enum) > 0 {
+		s["enum"] = arg.Enum
+	}
+	if arg.Default != nil {
+		s["default"] = arg.Default
+	}
+	if arg.Format != "" {
+		s["format"] = arg.Format
+	}
+	if arg.Pattern != "" {
+		s["pattern"] = arg.Pattern
+	}
+	for key, v := range map[string]*float64{"minimum": arg.Minimum, "maximum": arg.Maximum} {
+		if v != nil {
+			s[key] = *v
+		}
+	}
+	for key, v := range map[string]*int{"minLength": arg.MinLength, "maxLength": arg.MaxLength, "minItems": arg.MinItems, "maxItems": arg.MaxItems} {
+		if v != nil {
+			s[key] = *v
+		}
+	}
+	if arg.Items != nil {
+		s["items"] = arg.Items
+	}
+	if arg.Properties != nil {
+		s["properties"] = arg.Properties
+	}
+	if len(arg.RequiredProperties) > 0 {
+		s["required"] = arg.RequiredProperties
+	}
+	return s
+}
+
+// withDefaults 返回补上默认值的参数，不修改调用方传入的 map
+func (r *route) withDefaults(args map[string]any) map[string]any {
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		out[k] = v
+	}
+	for _, arg := range r.Args {
+		if out[arg.Name] == nil && arg.Default != nil {
+			out[arg.Name] = arg.Default
+		}
+	}
+	return out
+}
+
+// validateArgs 按参数的 schema 校验入参，错误信息中带上出错字段的路径，如 items[1].sku
+func (r *route) validateArgs(args map[string]any) error {
+	var errs []string
+	for _, arg := range r.Args {
+		v := args[arg.Name]
+		if v == nil {
+			if arg.Required {
+				errs = append(errs, arg.Name+": is required")
+			}
+			continue
+		}
+		if arg.File != nil {
+			if msg := checkFileArg(v); msg != "" {
+				errs = append(errs, arg.Name+": "+msg)
+			}
+			continue
+		}
+		// null 和未传入同样处理
+		errs = append(errs, jsonschema.Validate(arg.schema(), v, arg.Name, jsonschema.Options{NullAsAbsent: true})...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid arguments:\n- %s", strings.Join(errs, "\n- "))
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const schemaConfig = `
+tools:
+  - name: search_orders
+    args:
+      - name: status
+        type: string
+        enum: [new, paid, shipped]
+        default: new
+      - name: limit
+        type: integer
+        minimum: 1
+        maximum: 100
+        default: 20
+      - name: email
+        type: string
+        format: email
+      - name: since
+        type: string
+        format: date
+      - name: code
+        type: string
+        pattern: "^[A-Z]{3}$"
+      - name: urgent
+        type: boolean
+      - name: tags
+        type: array
+        maxItems: 2
+        items: {type: string, minLength: 2}
+      - name: filter
+        type: object
+        requiredProperties: [field]
+        properties:
+          field: {type: string}
+          range:
+            type: object
+            properties:
+              from: {type: number, minimum: 0}
+    requestTemplate:
+      url: URL/orders
+      argsToUrlParam: true
+`
+
+func TestValidateArgs(t *testing.T) {
+	cfg, err := parseConfig([]byte(strings.Replace(schemaConfig, "URL", "http://localhost", 1)))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	r, err := newRoute(cfg.Tools[0], nil)
+	if err != nil {
+		t.Fatalf("failed to create route: %v", err)
+	}
+
+	valid := map[string]any{
+		"status": "paid",
+		"limit":  float64(10),
+		"email":  "a@example.com",
+		"since":  "2024-01-31",
+		"code":   "ABC",
+		"urgent": true,
+		"tags":   []any{"vip", "cn"},
+		"filter": map[string]any{"field": "amount", "range": map[string]any{"from": float64(1.5)}},
+	}
+	if err := r.validateArgs(valid); err != nil {
+		t.Errorf("expected valid args, got %v", err)
+	}
+
+	tests := map[string]struct {
+		args map[string]any
+		want string
+	}{
+		"enum":          {map[string]any{"status": "lost"}, `status: must be one of ["new","paid","shipped"]`},
+		"integer":       {map[string]any{"limit": 1.5}, "limit: expected integer, got number"},
+		"maximum":       {map[string]any{"limit": float64(101)}, "limit: must be <= 100"},
+		"string type":   {map[string]any{"limit": "10"}, "limit: expected integer, got string"},
+		"email":         {map[string]any{"email": "not-an-email"}, "email: must be a valid email"},
+		"date":          {map[string]any{"since": "31/01/2024"}, "since: must be a valid date"},
+		"pattern":       {map[string]any{"code": "abc"}, "code: must match pattern ^[A-Z]{3}$"},
+		"boolean":       {map[string]any{"urgent": "yes"}, "urgent: expected boolean, got string"},
+		"max items":     {map[string]any{"tags": []any{"aa", "bb", "cc"}}, "tags: must have at most 2 items"},
+		"item":          {map[string]any{"tags": []any{"aa", "b"}}, "tags[1]: must be at least 2 characters"},
+		"nested req":    {map[string]any{"filter": map[string]any{}}, "filter.field: is required"},
+		"nested nested": {map[string]any{"filter": map[string]any{"field": "x", "range": map[string]any{"from": float64(-1)}}}, "filter.range.from: must be >= 0"},
+	}
+	for name, tt := range tests {
+		err := r.validateArgs(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", name, tt.want, err)
+		}
+	}
+
+	// 生成的 input schema 带上类型和约束
+	b, _ := json.Marshal(r.mcpTool().InputSchema)
+	var schema struct {
+		Properties map[string]map[string]any `json:"properties"`
+	}
+	json.Unmarshal(b, &schema)
+	if limit := schema.Properties["limit"]; limit["type"] != "integer" || limit["maximum"] != float64(100) || limit["default"] != float64(20) {
+		t.Errorf("unexpected limit schema %v", limit)
+	}
+	if tags := schema.Properties["tags"]; tags["type"] != "array" || tags["items"] == nil {
+		t.Errorf("unexpected tags schema %v", tags)
+	}
+	if filter := schema.Properties["filter"]; filter["type"] != "object" || filter["required"] == nil {
+		t.Errorf("unexpected filter schema %v", filter)
+	}
+
+	if _, err := parseConfig([]byte(`tools: [{name: a, args: [{name: x, pattern: "("}], requestTemplate: {url: http://localhost}}]`)); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestValidationBeforeUpstream(t *testing.T) {
+	var calls atomic.Int32
+	var query atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		query.Store(r.URL.RawQuery)
+	}))
+	defer upstream.Close()
+
+	cfg, err := parseConfig([]byte(strings.Replace(schemaConfig, "URL", upstream.URL, 1)))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	r, err := newRoute(cfg.Tools[0], nil)
+	if err != nil {
+		t.Fatalf("failed to create route: %v", err)
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"limit": float64(0), "status": "lost"}
+	result, err := r.handle(context.Background(), request)
+	if err != nil {
+		t.Fatalf("expected tool error, got protocol error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !result.IsError || !strings.Contains(text, "limit: must be >= 1") || !strings.Contains(text, "status: must be one of") {
+		t.Errorf("unexpected validation result %q", text)
+	}
+	if calls.Load() != 0 {
+		t.Error("expected invalid call not to reach upstream")
+	}
+
+	// 未传入的参数使用默认值
+	request.Params.Arguments = map[string]any{}
+	if result, err := r.handle(context.Background(), request); err != nil || result.IsError {
+		t.Fatalf("expected success, got %v %+v", err, result)
+	}
+	if q := query.Load(); q != "limit=20&status=new" {
+		t.Errorf("expected defaults in query, got %v", q)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// setCredentialsTool 是为当前会话设置上游凭证的 tool，有上游配置了 sessionAuth 时注册
+const setCredentialsTool = "set_upstream_credentials"
+
+// sessionIdleTimeout 之内没有使用的会话凭证会被清理。streamable http 的会话没有断开事件，只能按空闲时间清理
+const sessionIdleTimeout = time.Hour
+
+// SessionAuthConfig 让每个 mcp 会话使用自己的上游凭证，后台按各自的权限处理请求。
+// 凭证可以放在 mcp 连接的请求头中，也可以调用 set_upstream_credentials 设置，
+// 会话有凭证时代替 auth 注入，没有时使用 auth
+type SessionAuthConfig struct {
+	// Header 是 mcp 客户端携带凭证的请求头，为空时只能通过 tool 设置
+	Header string `json:"header"`
+	// In 为 header（默认）或 query，Name 为注入到上游请求的 header 名或 query 参数名，
+	// Name 为空时注入 Authorization: Bearer <凭证>
+	In     string `json:"in"`
+	Name   string `json:"name"`
+	Prefix string `json:"prefix"`
+	// Required 为 true 时会话没有凭证的调用直接返回 tool 错误，不使用 auth
+	Required bool `json:"required"`
+}
+
+type sessionAuth struct {
+	SessionAuthConfig
+}
+
+func newSessionAuth(cfg *SessionAuthConfig) (*sessionAuth, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	a := &sessionAuth{*cfg}
+	if a.In == "" {
+		a.In = positionHeader
+	}
+	if a.In != positionHeader && a.In != positionQuery {
+		return nil, fmt.Errorf("sessionAuth: in must be header or query, got %q", a.In)
+	}
+	if a.Name == "" {
+		if a.In == positionQuery {
+			return nil, fmt.Errorf("sessionAuth: name is required for query credentials")
+		}
+		a.Name, a.Prefix = "Authorization", "Bearer "
+	}
+	return a, nil
+}
+
+func (a *sessionAuth) apply(req *http.Request, credential string) {
+	if a.In == positionQuery {
+		q := req.URL.Query()
+		q.Set(a.Name, a.Prefix+credential)
+		req.URL.RawQuery = q.Encode()
+		return
+	}
+	req.Header.Set(a.Name, a.Prefix+credential)
+}
+
+// missingCredentialsError 表示上游要求会话凭证，但当前会话没有提供
+type missingCredentialsError struct {
+	upstream string
+	header   string
+}
+
+func (e *missingCredentialsError) Error() string {
+	if e.header != "" {
+		return fmt.Sprintf("upstream %s requires credentials for this session: send the %s header or call %s", e.upstream, e.header, setCredentialsTool)
+	}
+	return fmt.Sprintf("upstream %s requires credentials for this session: call %s", e.upstream, setCredentialsTool)
+}
+
+// sessionStore 按会话保存各上游的凭证
+type sessionStore struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*sessionCredentials
+}
+
+type sessionCredentials struct {
+	values   map[string]string
+	lastUsed time.Time
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{now: time.Now, sessions: map[string]*sessionCredentials{}}
+}
+
+// sessionIDFrom 返回当前请求所属的 mcp 会话，不在会话中时返回空字符串
+func sessionIDFrom(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// set 保存会话的凭证，credential 为空时清除。顺便清理空闲的会话
+func (s *sessionStore) set(sessionID, upstream, credential string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for id, c := range s.sessions {
+		if now.Sub(c.lastUsed) > sessionIdleTimeout {
+			delete(s.sessions, id)
+		}
+	}
+	c := s.sessions[sessionID]
+	if c == nil {
+		if credential == "" {
+			return
+		}
+		c = &sessionCredentials{values: map[string]string{}}
+		s.sessions[sessionID] = c
+	}
+	c.lastUsed = now
+	if credential == "" {
+		delete(c.values, upstream)
+		return
+	}
+	c.values[upstream] = credential
+}
+
+func (s *sessionStore) get(sessionID, upstream string) (string, bool) {
+	if s == nil || sessionID == "" {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.sessions[sessionID]
+	if c == nil {
+		return "", false
+	}
+	c.lastUsed = s.now()
+	credential, ok := c.values[upstream]
+	return credential, ok
+}
+
+func (s *sessionStore) remove(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionID)
+}
+
+// sessionContext 读取 mcp 请求头中的上游凭证并记到当前会话上，作为 sse 和 streamable http 的 context func
+func (a *adapter) sessionContext(ctx context.Context, r *http.Request) context.Context {
+	id := sessionIDFrom(ctx)
+	if id == "" {
+		return ctx
+	}
+	for _, u := range a.upstreams {
+		if u.sessionAuth == nil || u.sessionAuth.Header == "" {
+			continue
+		}
+		if credential := r.Header.Get(u.sessionAuth.Header); credential != "" {
+			a.sessions.set(id, u.name, credential)
+		}
+	}
+	return ctx
+}
+
+// hooks 在 sse 会话断开时清除它的凭证
+func (a *adapter) hooks() *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddOnUnregisterSession(func(_ context.Context, session server.ClientSession) {
+		a.sessions.remove(session.SessionID())
+	})
+	return hooks
+}
+
+// sessionAuthUpstreams 返回配置了 sessionAuth 的上游名称
+func (a *adapter) sessionAuthUpstreams() []string {
+	var names []string
+	for name, u := range a.upstreams {
+		if u.sessionAuth != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *adapter) credentialsTool() mcp.Tool {
+	return mcp.NewTool(setCredentialsTool,
+		mcp.WithDescription("Set the credentials used to call an upstream service for the rest of this session. An empty credential clears it."),
+		mcp.WithString("upstream", mcp.Required(), mcp.Enum(a.sessionAuthUpstreams()...)),
+		mcp.WithString("credential", mcp.Required(), mcp.Description("token, api key or other secret for the upstream")),
+	)
+}
+
+func (a *adapter) setCredentials(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id := sessionIDFrom(ctx)
+	if id == "" {
+		return mcp.NewToolResultError("credentials can only be set within a session"), nil
+	}
+	name := request.GetString("upstream", "")
+	if u := a.upstreams[name]; u == nil || u.sessionAuth == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("upstream %q does not accept session credentials", name)), nil
+	}
+	credential := request.GetString("credential", "")
+	a.sessions.set(id, name, credential)
+	if credential == "" {
+		return mcp.NewToolResultText(fmt.Sprintf("credentials for upstream %s cleared", name)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("credentials for upstream %s set for this session", name)), nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestSessionCredentials(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg, err := parseConfig([]byte(`
+upstreams:
+  crm:
+    baseURL: ` + upstream.URL + `
+    sessionAuth: {header: X-Crm-Token, required: true}
+tools:
+  - name: whoami
+    requestTemplate: {upstream: crm, url: /me}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	s := server.NewMCPServer("test", "1.0.0", server.WithHooks(a.hooks()))
+	a.register(s)
+	sse := server.NewTestServer(s, server.WithSSEContextFunc(a.sessionContext))
+	defer sse.Close()
+	streamable := server.NewTestStreamableHTTPServer(s, server.WithHTTPContextFunc(a.sessionContext))
+	defer streamable.Close()
+
+	connect := func(t *testing.T, transportName string, headers map[string]string) *client.Client {
+		var c *client.Client
+		var err error
+		if transportName == "sse" {
+			c, err = client.NewSSEMCPClient(sse.URL+"/sse", transport.WithHeaders(headers))
+		} else {
+			c, err = client.NewStreamableHttpClient(streamable.URL+"/mcp", transport.WithHTTPHeaders(headers))
+		}
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		ctx := context.Background()
+		if err := c.Start(ctx); err != nil {
+			t.Fatalf("failed to start client: %v", err)
+		}
+		if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+			t.Fatalf("failed to initialize: %v", err)
+		}
+		return c
+	}
+	call := func(c *client.Client, name string, args map[string]any) *mcp.CallToolResult {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = name
+		request.Params.Arguments = args
+		result, err := c.CallTool(context.Background(), request)
+		if err != nil {
+			t.Fatalf("failed to call %s: %v", name, err)
+		}
+		return result
+	}
+	text := func(result *mcp.CallToolResult) string {
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	for _, transportName := range []string{"sse", "streamable"} {
+		t.Run(transportName, func(t *testing.T) {
+			// 每个会话使用自己请求头中的凭证
+			alice := connect(t, transportName, map[string]string{"X-Crm-Token": "alice"})
+			bob := connect(t, transportName, map[string]string{"X-Crm-Token": "bob"})
+			if got := text(call(alice, "whoami", nil)); got != "Bearer alice" {
+				t.Errorf("alice: got %q", got)
+			}
+			if got := text(call(bob, "whoami", nil)); got != "Bearer bob" {
+				t.Errorf("bob: got %q", got)
+			}
+
+			// 没有凭证时返回 tool 错误，通过 tool 设置后生效
+			anonymous := connect(t, transportName, nil)
+			result := call(anonymous, "whoami", nil)
+			if !result.IsError || !strings.Contains(text(result), "send the X-Crm-Token header or call set_upstream_credentials") {
+				t.Errorf("expected missing credentials error, got %+v", result)
+			}
+			if result := call(anonymous, setCredentialsTool, map[string]any{"upstream": "crm", "credential": "carol"}); result.IsError {
+				t.Fatalf("failed to set credentials: %+v", result)
+			}
+			if got := text(call(anonymous, "whoami", nil)); got != "Bearer carol" {
+				t.Errorf("carol: got %q", got)
+			}
+			if got := text(call(alice, "whoami", nil)); got != "Bearer alice" {
+				t.Errorf("expected other sessions to be unaffected, got %q", got)
+			}
+			call(anonymous, setCredentialsTool, map[string]any{"upstream": "crm", "credential": ""})
+			if result := call(anonymous, "whoami", nil); !result.IsError {
+				t.Errorf("expected cleared credentials to be rejected, got %+v", result)
+			}
+		})
+	}
+
+	// 没有会话的调用使用 auth，要求会话凭证时返回 tool 错误
+	result, err := a.routes[0].handle(context.Background(), mcp.CallToolRequest{})
+	if err != nil || !result.IsError {
+		t.Errorf("expected missing credentials error without a session, got %v %+v", err, result)
+	}
+	if _, err := parseConfig([]byte(`
+upstreams:
+  crm: {baseURL: http://localhost, sessionAuth: {header: X-Crm-Token}}
+tools:
+  - {name: set_upstream_credentials, requestTemplate: {url: http://localhost}}
+`)); err == nil {
+		t.Error("expected error for reserved tool name")
+	}
+}
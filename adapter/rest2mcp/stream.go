@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// 流式响应的格式
+const (
+	streamSSE    = "sse"
+	streamNDJSON = "ndjson"
+)
+
+// StreamConfig 描述流式接口：上游以 server-sent events 或 NDJSON 逐块返回结果，
+// adapter 每收到一块就向客户端发送一条通知，结束后把所有块拼接成 tool 结果
+type StreamConfig struct {
+	// Format 为 sse 或 ndjson，为空时按响应的 Content-Type 判断
+	Format string `json:"format"`
+	// Select 是 JSONPath，从每一块 json 中取出要转发的内容，如 $.choices[0].delta.content；没有选中的块被忽略
+	Select string `json:"select"`
+	// Done 是表示流结束的块，如 [DONE]
+	Done string `json:"done"`
+	// Separator 是拼接最终结果时块之间的分隔符，默认换行
+	Separator *string `json:"separator"`
+}
+
+// streamReader 是解析好的 StreamConfig
+type streamReader struct {
+	StreamConfig
+	selector *jsonPath
+}
+
+// maxChunkSize 是单个 sse 事件或 ndjson 行的上限
+const maxChunkSize = 1 << 20
+
+func newStreamReader(t ToolConfig) (*streamReader, error) {
+	if t.Stream == nil {
+		return nil, nil
+	}
+	s := &streamReader{StreamConfig: *t.Stream}
+	if s.Format != "" && s.Format != streamSSE && s.Format != streamNDJSON {
+		return nil, fmt.Errorf("tool %s: unsupported stream format %q", t.Name, s.Format)
+	}
+	if t.ResponseTemplate.Select != "" || t.ResponseTemplate.Body != "" {
+		return nil, fmt.Errorf("tool %s: responseTemplate.select and body can not be used with stream, use stream.select instead", t.Name)
+	}
+	if s.Select != "" {
+		var err error
+		if s.selector, err = compileJSONPath(s.Select); err != nil {
+			return nil, fmt.Errorf("tool %s: stream.select: %v", t.Name, err)
+		}
+	}
+	return s, nil
+}
+
+// formatOf 返回响应的流格式，不是流式响应时返回空字符串
+func (s *streamReader) formatOf(resp *http.Response) string {
+	if s == nil {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/event-stream":
+		return streamSSE
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return streamNDJSON
+	}
+	return s.Format
+}
+
+// read 逐块读取流式响应并交给 ctx 中的 chunkSink，返回拼接后的结果
+func (s *streamReader) read(ctx context.Context, format string, body io.Reader) ([]byte, error) {
+	sink := chunkSinkFrom(ctx)
+	separator := "\n"
+	if s.Separator != nil {
+		separator = *s.Separator
+	}
+
+	var out strings.Builder
+	n := 0
+	emit := func(raw string) bool {
+		if s.Done != "" && strings.TrimSpace(raw) == s.Done {
+			return false
+		}
+		chunk, ok := s.extract(raw)
+		if !ok {
+			return true
+		}
+		if n > 0 {
+			out.WriteString(separator)
+		}
+		out.WriteString(chunk)
+		n++
+		if sink != nil {
+			sink(n, chunk)
+		}
+		return true
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxChunkSize)
+	var event []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if format == streamNDJSON {
+			if strings.TrimSpace(line) != "" && !emit(line) {
+				return []byte(out.String()), nil
+			}
+			continue
+		}
+		// sse：连续的 data 行组成一个事件，空行表示事件结束，其他字段和注释忽略
+		switch {
+		case line == "":
+			if len(event) > 0 && !emit(strings.Join(event, "\n")) {
+				return []byte(out.String()), nil
+			}
+			event = event[:0]
+		case strings.HasPrefix(line, "data:"):
+			event = append(event, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if n > 0 {
+			return nil, &streamInterruptedError{chunks: n, err: err}
+		}
+		return nil, fmt.Errorf("failed to read stream: %v", err)
+	}
+	if len(event) > 0 {
+		emit(strings.Join(event, "\n"))
+	}
+	return []byte(out.String()), nil
+}
+
+// streamInterruptedError 表示流在转发了部分块之后中断，此时不能重试，否则客户端会重复收到已经转发的块
+type streamInterruptedError struct {
+	chunks int
+	err    error
+}
+
+func (e *streamInterruptedError) Error() string {
+	return fmt.Sprintf("stream interrupted after %d chunks: %v", e.chunks, e.err)
+}
+
+// extract 按 select 取出块中要转发的内容，不是 json 的块原样转发
+func (s *streamReader) extract(raw string) (string, bool) {
+	if s.selector == nil {
+		return raw, true
+	}
+	var data any
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return raw, true
+	}
+	v, ok := s.selector.eval(data)
+	if !ok || v == nil {
+		return "", false
+	}
+	if str, ok := v.(string); ok {
+		return str, true
+	}
+	b, _ := json.Marshal(v)
+	return string(b), true
+}
+
+type chunkSinkKey struct{}
+
+// chunkSink 接收流式响应中的一块，n 从 1 开始
+type chunkSink func(n int, chunk string)
+
+func withChunkSink(ctx context.Context, sink chunkSink) context.Context {
+	if sink == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, chunkSinkKey{}, sink)
+}
+
+func chunkSinkFrom(ctx context.Context) chunkSink {
+	sink, _ := ctx.Value(chunkSinkKey{}).(chunkSink)
+	return sink
+}
+
+// chunkSink 把每一块作为通知发给客户端：调用带有 progressToken 时发送 notifications/progress，
+// 否则发送 notifications/message 日志通知
+func (r *route) chunkSink(ctx context.Context, request mcp.CallToolRequest) chunkSink {
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return nil
+	}
+	var token mcp.ProgressToken
+	if request.Params.Meta != nil {
+		token = request.Params.Meta.ProgressToken
+	}
+	return func(n int, chunk string) {
+		var err error
+		if token != nil {
+			err = srv.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+				"progressToken": token,
+				"progress":      float64(n),
+				"message":       chunk,
+			})
+		} else {
+			err = srv.SendNotificationToClient(ctx, "notifications/message", map[string]any{
+				"level":  mcp.LoggingLevelInfo,
+				"logger": r.Name,
+				"data":   chunk,
+			})
+		}
+		if err != nil {
+			log.Printf("tool %s: failed to forward stream chunk: %v", r.Name, err)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func newStreamUpstream(t *testing.T) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		switch r.URL.Path {
+		case "/chat":
+			w.Header().Set("Content-Type", "text/event-stream")
+			// 与真实的流式接口一样逐块间隔返回，streamable http 在调用返回时会丢弃还没有写出的通知
+			for _, word := range []string{"Hello", ", ", "world"} {
+				fmt.Fprintf(w, "event: delta\ndata: {\"delta\":%q}\n\n", word)
+				flusher.Flush()
+				time.Sleep(20 * time.Millisecond)
+			}
+			fmt.Fprint(w, ": keep-alive\n\ndata: {\"usage\":3}\n\ndata: [DONE]\n\n")
+		case "/export":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			for i := 1; i <= 3; i++ {
+				fmt.Fprintf(w, "{\"id\":%d}\n", i)
+				flusher.Flush()
+			}
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestStreamRead(t *testing.T) {
+	upstream := newStreamUpstream(t)
+	cfg, err := parseConfig([]byte(`
+upstreams:
+  svc:
+    baseURL: ` + upstream.URL + `
+tools:
+  - name: chat
+    requestTemplate: {upstream: svc, url: /chat}
+    stream: {select: $.delta, done: "[DONE]", separator: ""}
+  - name: export
+    requestTemplate: {upstream: svc, url: /export}
+    stream: {}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	want := map[string]string{"chat": "Hello, world", "export": "{\"id\":1}\n{\"id\":2}\n{\"id\":3}"}
+	for _, r := range a.routes {
+		var chunks []string
+		ctx := withChunkSink(context.Background(), func(n int, chunk string) { chunks = append(chunks, chunk) })
+		resp, err := r.send(ctx, nil)
+		if err != nil {
+			t.Fatalf("%s: failed to send: %v", r.Name, err)
+		}
+		if !resp.streamed || string(resp.body) != want[r.Name] {
+			t.Errorf("%s: unexpected aggregated body %q", r.Name, resp.body)
+		}
+		if r.Name == "chat" && fmt.Sprint(chunks) != "[Hello ,  world]" {
+			t.Errorf("unexpected chunks %q", chunks)
+		}
+	}
+
+	if _, err := parseConfig([]byte(`tools: [{name: a, requestTemplate: {url: http://localhost}, stream: {format: xml}}]`)); err == nil {
+		t.Error("expected error for unsupported stream format")
+	}
+}
+
+func TestStreamNotifications(t *testing.T) {
+	upstream := newStreamUpstream(t)
+	cfg, err := parseConfig([]byte(`
+tools:
+  - name: chat
+    requestTemplate: {url: ` + upstream.URL + `/chat}
+    stream: {select: $.delta, done: "[DONE]", separator: ""}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	s := server.NewMCPServer("test", "1.0.0", server.WithLogging())
+	a.register(s)
+
+	transports := map[string]func() (*client.Client, func()){
+		"sse": func() (*client.Client, func()) {
+			ts := server.NewTestServer(s)
+			c, err := client.NewSSEMCPClient(ts.URL + "/sse")
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+			return c, ts.Close
+		},
+		"streamable": func() (*client.Client, func()) {
+			ts := server.NewTestStreamableHTTPServer(s)
+			c, err := client.NewStreamableHttpClient(ts.URL + "/mcp")
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+			return c, ts.Close
+		},
+	}
+	for name, connect := range transports {
+		t.Run(name, func(t *testing.T) {
+			c, closeServer := connect()
+			defer closeServer()
+			defer c.Close()
+
+			var mu sync.Mutex
+			var messages []string
+			c.OnNotification(func(n mcp.JSONRPCNotification) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch n.Method {
+				case "notifications/progress":
+					messages = append(messages, fmt.Sprint(n.Params.AdditionalFields["message"]))
+				case "notifications/message":
+					messages = append(messages, fmt.Sprint(n.Params.AdditionalFields["data"]))
+				}
+			})
+			ctx := context.Background()
+			if err := c.Start(ctx); err != nil {
+				t.Fatalf("failed to start client: %v", err)
+			}
+			if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+				t.Fatalf("failed to initialize: %v", err)
+			}
+
+			request := mcp.CallToolRequest{}
+			request.Params.Name = "chat"
+			request.Params.Meta = &mcp.Meta{ProgressToken: "chat-1"}
+			result, err := c.CallTool(ctx, request)
+			if err != nil {
+				t.Fatalf("failed to call tool: %v", err)
+			}
+			if text := result.Content[0].(mcp.TextContent).Text; text != "Hello, world" {
+				t.Errorf("unexpected result %q", text)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if fmt.Sprint(messages) != "[Hello ,  world]" {
+				t.Errorf("unexpected notifications %q", messages)
+			}
+		})
+	}
+}
+
+func TestStreamInterrupted(t *testing.T) {
+	// 转发过块之后流中断时不重试，客户端不会重复收到已经转发的块
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprint(w, "{\"id\":1}\n")
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("failed to hijack: %v", err)
+			return
+		}
+		conn.Close()
+	}))
+	defer upstream.Close()
+
+	cfg, err := parseConfig([]byte(`
+tools:
+  - name: export
+    requestTemplate: {url: ` + upstream.URL + `/export}
+    retry: {attempts: 3, backoff: 1ms}
+    stream: {}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	a, err := newAdapter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	var chunks []string
+	ctx := withChunkSink(context.Background(), func(n int, chunk string) { chunks = append(chunks, chunk) })
+	if _, err := a.routes[0].send(ctx, nil); err == nil || !strings.Contains(err.Error(), "stream interrupted after 1 chunks") {
+		t.Errorf("expected interrupted stream error, got %v", err)
+	}
+	if n := calls.Load(); n != 1 || len(chunks) != 1 {
+		t.Errorf("expected 1 call and 1 chunk, got %d calls and chunks %q", n, chunks)
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// 请求模板中可以使用的函数，模板数据为 {"args": 入参}：
+//
+//	{{json .args.name}}         json 编码，用于拼接 json 请求体
+//	{{pathEscape .args.id}}     按 url path 转义
+//	{{queryEscape .args.q}}     按 url query 转义，与内置的 urlquery 相同
+//	{{jsonEscape .args.name}}   按 json 字符串转义但不加引号，用于拼接在 json 字符串中间
+//	{{jsonpath . "$.items[*]"}} 按 JSONPath 取值，常用于响应模板
+//
+// url 中的 {id} 占位符是 {{pathParam . "id"}} 的简写，参数缺失时请求失败。
+// 入参中的数字在模板数据中是 number 类型，{{.args.limit}} 不会输出成科学计数法。
+// query 和 headers 的模板引用了未传入的参数时，整个 key 会被丢弃，可选参数不会变成空值
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"pathEscape": func(v any) string {
+		return url.PathEscape(stringify(v))
+	},
+	"queryEscape": func(v any) string {
+		return url.QueryEscape(stringify(v))
+	},
+	"jsonEscape": func(v any) string {
+		b, _ := json.Marshal(stringify(v))
+		return string(b[1 : len(b)-1])
+	},
+	"jsonpath": func(data any, expr string) (any, error) {
+		p, err := compileJSONPath(expr)
+		if err != nil {
+			return nil, err
+		}
+		v, _ := p.eval(data)
+		return v, nil
+	},
+	"pathParam": func(data map[string]any, name string) (string, error) {
+		args, _ := data["args"].(map[string]any)
+		v, ok := args[name]
+		if !ok {
+			return "", fmt.Errorf("missing path parameter: %s", name)
+		}
+		return url.PathEscape(stringify(v)), nil
+	},
+}
+
+// parseTemplate 解析模板，strict 为 true 时引用不存在的参数会报错而不是输出 <no value>
+func parseTemplate(name, text string, strict bool) (*template.Template, error) {
+	tmpl := template.New(name).Funcs(templateFuncs)
+	if strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s: %v", name, err)
+	}
+	return tmpl, nil
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_.\-]*)\}`)
+
+// expandPathParams 把 /users/{id} 中的占位符替换成 pathParam 调用，跳过 {{ }} 模板动作
+func expandPathParams(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range pathParamPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[0], m[1]
+		if start > 0 && text[start-1] == '{' || end < len(text) && text[end] == '}' {
+			continue
+		}
+		b.WriteString(text[last:start])
+		fmt.Fprintf(&b, `{{pathParam . %q}}`, text[m[2]:m[3]])
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func render(tmpl *template.Template, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %v", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// renderOptional 渲染 query 或 header 模板，模板引用了未传入的参数时返回 false
+func renderOptional(tmpl *template.Template, data any) (string, bool, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		if strings.Contains(err.Error(), "map has no entry for key") {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to render template %s: %v", tmpl.Name(), err)
+	}
+	return buf.String(), true, nil
+}
+
+// positionOf 返回参数在请求中的位置，未声明时由 argsToUrlParam 决定放在 query 还是请求体中
+func (r *route) positionOf(arg ArgConfig) string {
+	if arg.Position != "" {
+		return arg.Position
+	}
+	if r.RequestTemplate.ArgsToUrlParam {
+		return positionQuery
+	}
+	return positionBody
+}
+
+// buildRequest 渲染请求模板，再按参数的 position 把入参填到 path、query、header 和 body 中，
+// 相对路径拼在 inst 的地址上
+func (r *route) buildRequest(ctx context.Context, args map[string]any, inst *instance) (*http.Request, error) {
+	// null 和未传入同样处理
+	present := make(map[string]any, len(args))
+	for k, v := range args {
+		if v != nil {
+			present[k] = v
+		}
+	}
+	data := map[string]any{"args": templateValue(present)}
+
+	query := url.Values{}
+	header := http.Header{}
+	bodyFields := map[string]any{}
+	var bodyValue any
+	for _, arg := range r.Args {
+		value, ok := present[arg.Name]
+		if !ok {
+			if arg.Required {
+				return nil, fmt.Errorf("missing required parameter: %s", arg.Name)
+			}
+			continue
+		}
+		if arg.Name == r.RequestTemplate.BodyArg {
+			bodyValue = value
+			continue
+		}
+		switch r.positionOf(arg) {
+		case positionQuery:
+			addValues(query, arg.Name, value)
+		case positionHeader:
+			header.Set(arg.Name, stringify(value))
+		case positionBody:
+			bodyFields[arg.Name] = value
+		}
+		// position 为 path 的参数由 url 中的占位符处理，template 的参数由模板处理
+	}
+
+	rawURL, err := render(r.url, data)
+	if err != nil {
+		return nil, err
+	}
+	if inst != nil {
+		rawURL = inst.resolve(rawURL)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %q: %v", rawURL, err)
+	}
+	// 翻页：link 模式直接请求下一页的地址，但只允许与本接口相同的 host，避免被引导去请求其他地址
+	page := pageRequestFrom(ctx)
+	if page != nil && page.url != "" {
+		next, err := url.Parse(page.url)
+		if err != nil || next.Scheme != u.Scheme || next.Host != u.Host {
+			return nil, fmt.Errorf("invalid page cursor %q: it does not belong to %s", page.url, u.Host)
+		}
+		u, query = next, url.Values{}
+	}
+	if page == nil || page.url == "" {
+		for i, p := range r.RequestTemplate.Query {
+			value, ok, err := renderOptional(r.query[i], data)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				query.Add(p.Key, value)
+			}
+		}
+	}
+	if len(query) > 0 || page != nil && len(page.query) > 0 {
+		q := u.Query()
+		for k, v := range query {
+			q[k] = append(q[k], v...)
+		}
+		// 翻页参数覆盖同名的参数
+		if page != nil {
+			for k, v := range page.query {
+				q[k] = v
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	// 构造请求体，配置了 body 模板时以模板为准
+	var body io.Reader
+	switch {
+	case r.graphql != nil:
+		reqBody, err := r.graphql.body(bodyFields)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(reqBody)
+		header.Set("Content-Type", "application/json")
+	case r.body != nil:
+		rendered, err := render(r.body, data)
+		if err != nil {
+			return nil, err
+		}
+		body = strings.NewReader(rendered)
+		if r.RequestTemplate.ArgsToFormBody {
+			header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			header.Set("Content-Type", "application/json")
+		}
+	case bodyValue != nil:
+		reqBody, err := json.Marshal(bodyValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %v", err)
+		}
+		body = bytes.NewReader(reqBody)
+		header.Set("Content-Type", "application/json")
+	case len(bodyFields) > 0 && r.RequestTemplate.ArgsToMultipartBody:
+		reqBody, contentType, err := r.multipartBody(ctx, data, bodyFields)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(reqBody)
+		header.Set("Content-Type", contentType)
+	case len(bodyFields) > 0 && r.RequestTemplate.ArgsToFormBody:
+		form := url.Values{}
+		for k, v := range bodyFields {
+			addValues(form, k, v)
+		}
+		body = strings.NewReader(form.Encode())
+		header.Set("Content-Type", "application/x-www-form-urlencoded")
+	case len(bodyFields) > 0:
+		reqBody, err := json.Marshal(bodyFields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %v", err)
+		}
+		body = bytes.NewReader(reqBody)
+		header.Set("Content-Type", "application/json")
+	}
+
+	// 配置的 header 覆盖按参数生成的 header，包括默认的 Content-Type
+	for i, h := range r.RequestTemplate.Headers {
+		value, ok, err := renderOptional(r.headers[i], data)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			header.Set(h.Key, value)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.RequestTemplate.Method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return req, nil
+}
+
+// addValues 添加 query 或表单参数，数组展开成同名的多个值
+func addValues(values url.Values, key string, v any) {
+	for _, item := range flatten(v) {
+		values.Add(key, stringify(item))
+	}
+}
+
+// flatten 把数组展开成多个值
+func flatten(v any) []any {
+	if list, ok := v.([]any); ok {
+		return list
+	}
+	return []any{v}
+}
+
+// number 是模板数据中的数字，输出时不使用科学计数法，比较和 json 编码与 float64 相同
+type number float64
+
+func (n number) String() string {
+	return strconv.FormatFloat(float64(n), 'f', -1, 64)
+}
+
+// templateValue 把入参中的数字换成 number，这样 {{.args.limit}} 输出 1000000 而不是 1e+06
+func templateValue(v any) any {
+	switch val := v.(type) {
+	case float64:
+		return number(val)
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = templateValue(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = templateValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// stringify 把 json 解码出来的值转换成放进 url 或 header 的字符串
+func stringify(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []any, map[string]any:
+		b, _ := json.Marshal(val)
+		return string(b)
+	case float64:
+		// json 中的数字都解码成 float64，fmt.Sprint 会把 12345678 输出成 1.2345678e+07
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func TestBuildRequest(t *testing.T) {
+	tool := ToolConfig{
+		Name: "search_orders",
+		Args: []ArgConfig{
+			{Name: "user", Type: "string", Required: true, Position: "path"},
+			{Name: "status", Type: "string"},
+			{Name: "tags", Type: "array"},
+			{Name: "trace", Type: "string"},
+			{Name: "note", Type: "string"},
+		},
+		RequestTemplate: RequestTemplate{
+			URL:    "http://localhost/users/{user}/orders",
+			Method: "post",
+			Query: []ParamConfig{
+				{Key: "status", Value: "{{.args.status}}"},
+				{Key: "v", Value: "2"},
+			},
+			Headers: []ParamConfig{
+				{Key: "X-Trace", Value: "{{.args.trace}}"},
+			},
+			Body: `{"note": {{json .args.note}}}`,
+		},
+	}
+	r, err := newRoute(tool, nil)
+	if err != nil {
+		t.Fatalf("failed to create route: %v", err)
+	}
+
+	t.Run("all args", func(t *testing.T) {
+		req, err := r.buildRequest(context.Background(), map[string]any{
+			"user":   "a b/c",
+			"status": "new&paid",
+			"trace":  "t-1",
+			"note":   `say "hi"`,
+		}, nil)
+		if err != nil {
+			t.Fatalf("failed to build request: %v", err)
+		}
+		if req.Method != "POST" {
+			t.Errorf("expected POST, got %s", req.Method)
+		}
+		if got := req.URL.String(); got != "http://localhost/users/a%20b%2Fc/orders?status=new%26paid&v=2" {
+			t.Errorf("unexpected url %s", got)
+		}
+		if got := req.Header.Get("X-Trace"); got != "t-1" {
+			t.Errorf("unexpected X-Trace header %q", got)
+		}
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != `{"note": "say \"hi\""}` {
+			t.Errorf("unexpected body %s", body)
+		}
+	})
+
+	t.Run("optional args omitted", func(t *testing.T) {
+		req, err := r.buildRequest(context.Background(), map[string]any{"user": "u1", "status": nil}, nil)
+		if err != nil {
+			t.Fatalf("failed to build request: %v", err)
+		}
+		if got := req.URL.String(); got != "http://localhost/users/u1/orders?v=2" {
+			t.Errorf("unexpected url %s", got)
+		}
+		if _, ok := req.Header["X-Trace"]; ok {
+			t.Error("expected X-Trace header to be dropped")
+		}
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != `{"note": null}` {
+			t.Errorf("unexpected body %s", body)
+		}
+	})
+
+	t.Run("missing path param", func(t *testing.T) {
+		if _, err := r.buildRequest(context.Background(), map[string]any{}, nil); err == nil {
+			t.Error("expected error for missing user")
+		}
+	})
+}
+
+func TestBuildRequestArgsPlacement(t *testing.T) {
+	args := map[string]any{"q": "go mcp", "tags": []any{"a", "b"}, "page": float64(2)}
+	argConfigs := []ArgConfig{{Name: "q"}, {Name: "tags"}, {Name: "page"}}
+
+	tests := []struct {
+		name        string
+		template    RequestTemplate
+		url         string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "json body",
+			template:    RequestTemplate{URL: "http://localhost/search", Method: "POST", ArgsToJsonBody: true},
+			url:         "http://localhost/search",
+			contentType: "application/json",
+			body:        `{"page":2,"q":"go mcp","tags":["a","b"]}`,
+		},
+		{
+			name:     "url params",
+			template: RequestTemplate{URL: "http://localhost/search?lang=zh", ArgsToUrlParam: true},
+			url:      "http://localhost/search?lang=zh&page=2&q=go+mcp&tags=a&tags=b",
+		},
+		{
+			name:        "form body",
+			template:    RequestTemplate{URL: "http://localhost/search", Method: "POST", ArgsToFormBody: true},
+			url:         "http://localhost/search",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "page=2&q=go+mcp&tags=a&tags=b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newRoute(ToolConfig{Name: "search", Args: argConfigs, RequestTemplate: tt.template}, nil)
+			if err != nil {
+				t.Fatalf("failed to create route: %v", err)
+			}
+			req, err := r.buildRequest(context.Background(), args, nil)
+			if err != nil {
+				t.Fatalf("failed to build request: %v", err)
+			}
+			if got := req.URL.String(); got != tt.url {
+				t.Errorf("unexpected url %s", got)
+			}
+			if got := req.Header.Get("Content-Type"); got != tt.contentType {
+				t.Errorf("unexpected content type %q", got)
+			}
+			var body []byte
+			if req.Body != nil {
+				body, _ = io.ReadAll(req.Body)
+			}
+			if string(body) != tt.body {
+				t.Errorf("unexpected body %s", body)
+			}
+		})
+	}
+
+	if _, err := newRoute(ToolConfig{Name: "bad", RequestTemplate: RequestTemplate{URL: "http://localhost", ArgsToUrlParam: true, ArgsToFormBody: true}}, nil); err == nil {
+		t.Error("expected error for conflicting args placement")
+	}
+}
+
+func TestBuildRequestNumbers(t *testing.T) {
+	r, err := newRoute(ToolConfig{
+		Name: "get_order",
+		Args: []ArgConfig{
+			{Name: "id", Type: "integer", Position: positionPath},
+			{Name: "limit", Type: "integer", Position: positionQuery},
+			{Name: "X-Ratio", Type: "number", Position: positionHeader},
+			{Name: "amount", Type: "number"},
+		},
+		RequestTemplate: RequestTemplate{URL: "http://localhost/orders/{id}", Method: "POST", ArgsToFormBody: true},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create route: %v", err)
+	}
+	args := map[string]any{"id": float64(12345678), "limit": float64(1000000), "X-Ratio": 0.25, "amount": 1e21}
+	req, err := r.buildRequest(context.Background(), args, nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if got := req.URL.String(); got != "http://localhost/orders/12345678?limit=1000000" {
+		t.Errorf("unexpected url %s", got)
+	}
+	if got := req.Header.Get("X-Ratio"); got != "0.25" {
+		t.Errorf("unexpected header %s", got)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != "amount=1000000000000000000000" {
+		t.Errorf("unexpected body %s", body)
+	}
+}
+
+func TestRenderTemplateNumbers(t *testing.T) {
+	// 模板中直接引用的数字同样不使用科学计数法，json 编码和比较不受影响
+	r, err := newRoute(ToolConfig{
+		Name: "list_orders",
+		Args: []ArgConfig{
+			{Name: "limit", Type: "integer", Position: positionTemplate},
+			{Name: "ids", Type: "array", Position: positionTemplate},
+		},
+		RequestTemplate: RequestTemplate{
+			URL:     "http://localhost/orders/{{index .args.ids 0}}",
+			Method:  "POST",
+			Query:   []ParamConfig{{Key: "page_size", Value: "{{.args.limit}}"}},
+			Headers: []ParamConfig{{Key: "X-Limit", Value: `{{if gt .args.limit 100.0}}{{.args.limit}}{{end}}`}},
+			Body:    `{"limit": {{.args.limit}}, "ids": {{json .args.ids}}}`,
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create route: %v", err)
+	}
+	args := map[string]any{"limit": float64(1000000), "ids": []any{float64(12345678), 0.5}}
+	req, err := r.buildRequest(context.Background(), args, nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if got := req.URL.String(); got != "http://localhost/orders/12345678?page_size=1000000" {
+		t.Errorf("unexpected url %s", got)
+	}
+	if got := req.Header.Get("X-Limit"); got != "1000000" {
+		t.Errorf("unexpected header %s", got)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != `{"limit": 1000000, "ids": [12345678,0.5]}` {
+		t.Errorf("unexpected body %s", body)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// UpstreamConfig 描述一个上游 rest 服务
+type UpstreamConfig struct {
+	// BaseURL 是上游地址，tool 的 url 为相对路径时拼在它后面
+	BaseURL string `json:"baseURL"`
+	// BaseURLs 是多副本部署时的所有实例地址，与 BaseURL 合并使用
+	BaseURLs    []string           `json:"baseURLs"`
+	LoadBalance LoadBalanceConfig  `json:"loadBalance"`
+	HealthCheck *HealthCheckConfig `json:"healthCheck"`
+	Auth        *AuthConfig        `json:"auth"`
+	// SessionAuth 让每个 mcp 会话使用自己的凭证，见 session.go
+	SessionAuth *SessionAuthConfig `json:"sessionAuth"`
+	// Breaker 覆盖 server.breaker 中的默认熔断策略
+	Breaker *BreakerConfig `json:"breaker"`
+	// RateLimit 限制发往这个上游的所有请求，见 ratelimit.go
+	RateLimit *RateLimitConfig `json:"rateLimit"`
+}
+
+type upstream struct {
+	name        string
+	instances   []*instance
+	balancer    balancer
+	health      *HealthCheckConfig
+	auth        authenticator
+	sessionAuth *sessionAuth
+	breaker     *BreakerConfig
+	limiter     *limiter
+}
+
+func newUpstream(name string, cfg UpstreamConfig, client *http.Client) (*upstream, error) {
+	baseURLs := cfg.BaseURLs
+	if cfg.BaseURL != "" {
+		baseURLs = append([]string{cfg.BaseURL}, baseURLs...)
+	}
+	if len(baseURLs) == 0 {
+		return nil, fmt.Errorf("upstream %s: baseURL is required", name)
+	}
+	u := &upstream{name: name, health: cfg.HealthCheck, breaker: cfg.Breaker}
+	for _, baseURL := range baseURLs {
+		if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+			return nil, fmt.Errorf("upstream %s: baseURL %q must be an absolute http url", name, baseURL)
+		}
+		u.instances = append(u.instances, newInstance(strings.TrimSuffix(baseURL, "/")))
+	}
+
+	var err error
+	if u.balancer, err = newBalancer(cfg.LoadBalance, u.instances); err != nil {
+		return nil, fmt.Errorf("upstream %s: %v", name, err)
+	}
+	if u.auth, err = newAuthenticator(cfg.Auth); err != nil {
+		return nil, fmt.Errorf("upstream %s: %v", name, err)
+	}
+	if o, ok := u.auth.(*oauth2Auth); ok {
+		o.client = client
+	}
+	if u.sessionAuth, err = newSessionAuth(cfg.SessionAuth); err != nil {
+		return nil, fmt.Errorf("upstream %s: %v", name, err)
+	}
+	if u.limiter, err = newLimiter("upstream "+name, cfg.RateLimit); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// pick 按负载均衡策略选择一个健康的实例，所有实例都不健康时退化为在全部实例中选择
+func (u *upstream) pick(args map[string]any) *instance {
+	return u.balancer.pick(args)
+}
+
+// resolve 把相对路径拼到实例地址上，绝对地址保持不变
+func (inst *instance) resolve(rawURL string) string {
+	if strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://") {
+		return rawURL
+	}
+	return inst.baseURL + "/" + strings.TrimPrefix(rawURL, "/")
+}
@@ -4,10 +4,19 @@ go 1.23.2
 
 toolchain go1.24.4
 
-require github.com/mark3labs/mcp-go v0.31.0
+require (
+	github.com/mark3labs/mcp-go v0.31.0
+	github.com/yosida95/uritemplate/v3 v3.0.2
+	google.golang.org/grpc v1.72.2
+	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/spf13/cast v1.7.1 // indirect
-	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+	golang.org/x/net v0.35.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
 )
@@ -0,0 +1,76 @@
+// Package config 是各个 adapter 读取配置文件时共用的部分：yaml 或 json 的解码，以及配置中的时长
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Decode 解析 yaml 或 json 内容（json 本身就是合法的 yaml），再按 json tag 填充 v
+func Decode(data []byte, v any) error {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	b, err := json.Marshal(stringKeys(raw))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// stringKeys 把 yaml 中的非字符串 key（如 responses 下的 200）转换成字符串，否则无法编码成 json
+func stringKeys(node any) any {
+	switch v := node.(type) {
+	case map[string]any:
+		for k, child := range v {
+			v[k] = stringKeys(child)
+		}
+		return v
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for k, child := range v {
+			out[fmt.Sprint(k)] = stringKeys(child)
+		}
+		return out
+	case []any:
+		for i, child := range v {
+			v[i] = stringKeys(child)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Duration 支持在配置中写 "30s"、"1m" 这样的时长，数字按秒处理
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch val := v.(type) {
+	case float64:
+		*d = Duration(val * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	case nil:
+		*d = 0
+	default:
+		return fmt.Errorf("invalid duration %s", b)
+	}
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
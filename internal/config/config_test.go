@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	var v struct {
+		Timeout  Duration          `json:"timeout"`
+		Interval Duration          `json:"interval"`
+		Codes    map[string]string `json:"codes"`
+	}
+	if err := Decode([]byte("timeout: 1m\ninterval: 2.5\ncodes: {404: not found, true: yes}\n"), &v); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if time.Duration(v.Timeout) != time.Minute || time.Duration(v.Interval) != 2500*time.Millisecond {
+		t.Errorf("unexpected durations %v %v", v.Timeout, v.Interval)
+	}
+	if v.Codes["404"] != "not found" || v.Codes["true"] != "yes" {
+		t.Errorf("unexpected codes %v", v.Codes)
+	}
+	if err := Decode([]byte(`{"timeout": "soon"}`), &v); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
@@ -0,0 +1,331 @@
+// Package jsonschema 是 rest2mcp 和 mcp2rest 共用的 json schema 校验，只覆盖 tool 入参中常见的关键字：
+// type、enum、const、数值和长度约束、pattern、format、required、properties、additionalProperties、items
+// 以及 allOf、anyOf、oneOf，不认识的关键字不做限制
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options 调整校验的细节
+type Options struct {
+	// NullAsAbsent 为 true 时对象中值为 null 的属性视为未传入：不满足 required，也不按属性的 schema 校验
+	NullAsAbsent bool
+}
+
+// Validate 按 schema 校验 v，返回所有不符合的地方，错误信息以出错字段的路径开头，如 "body.items[1].sku: is required"
+func Validate(schema map[string]any, v any, path string, opts Options) []string {
+	var errs []string
+	opts.validate(schema, v, path, &errs)
+	return errs
+}
+
+func (o Options) validate(schema map[string]any, v any, path string, errs *[]string) {
+	fail := func(format string, a ...any) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, a...))
+	}
+
+	if types := StringList(schema["type"]); len(types) > 0 && !hasAnyType(v, types) {
+		fail("expected %s, got %s", strings.Join(types, " or "), TypeOf(v))
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !inEnum(v, enum) {
+		b, _ := json.Marshal(enum)
+		fail("must be one of %s", b)
+	}
+	if c, ok := schema["const"]; ok && !inEnum(v, []any{c}) {
+		b, _ := json.Marshal(c)
+		fail("must be %s", b)
+	}
+	for _, sub := range schemaList(schema["allOf"]) {
+		o.validate(sub, v, path, errs)
+	}
+	if subs := schemaList(schema["anyOf"]); len(subs) > 0 && o.matching(subs, v) == 0 {
+		fail("must match at least one schema in anyOf")
+	}
+	if subs := schemaList(schema["oneOf"]); len(subs) > 0 && o.matching(subs, v) != 1 {
+		fail("must match exactly one schema in oneOf")
+	}
+
+	switch val := v.(type) {
+	case string:
+		n := len([]rune(val))
+		if min, ok := intOf(schema["minLength"]); ok && n < min {
+			fail("must be at least %d characters", min)
+		}
+		if max, ok := intOf(schema["maxLength"]); ok && n > max {
+			fail("must be at most %d characters", max)
+		}
+		if p, ok := schema["pattern"].(string); ok {
+			if re, err := compilePattern(p); err == nil && !re.MatchString(val) {
+				fail("must match pattern %s", p)
+			}
+		}
+		if f, ok := schema["format"].(string); ok && !matchesFormat(f, val) {
+			fail("must be a valid %s", f)
+		}
+	case float64:
+		if min, ok := floatOf(schema["minimum"]); ok && val < min {
+			fail("must be >= %v", min)
+		}
+		if max, ok := floatOf(schema["maximum"]); ok && val > max {
+			fail("must be <= %v", max)
+		}
+		if min, ok := floatOf(schema["exclusiveMinimum"]); ok && val <= min {
+			fail("must be > %v", min)
+		}
+		if max, ok := floatOf(schema["exclusiveMaximum"]); ok && val >= max {
+			fail("must be < %v", max)
+		}
+	case []any:
+		if min, ok := intOf(schema["minItems"]); ok && len(val) < min {
+			fail("must have at least %d items", min)
+		}
+		if max, ok := intOf(schema["maxItems"]); ok && len(val) > max {
+			fail("must have at most %d items", max)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range val {
+				o.validate(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case map[string]any:
+		for _, name := range StringList(schema["required"]) {
+			if v, ok := val[name]; !ok || o.NullAsAbsent && v == nil {
+				*errs = append(*errs, path+"."+name+": is required")
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		names := make([]string, 0, len(val))
+		for name := range val {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if o.NullAsAbsent && val[name] == nil {
+				continue
+			}
+			if prop, ok := props[name].(map[string]any); ok {
+				o.validate(prop, val[name], path+"."+name, errs)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					*errs = append(*errs, path+"."+name+": is not allowed")
+				}
+			case map[string]any:
+				o.validate(additional, val[name], path+"."+name, errs)
+			}
+		}
+	}
+}
+
+// Check 在加载配置时检查 schema 中的正则表达式，避免校验时才发现错误
+func Check(schema map[string]any, path string) error {
+	if p, ok := schema["pattern"].(string); ok {
+		if _, err := compilePattern(p); err != nil {
+			return fmt.Errorf("%s: invalid pattern %q: %v", path, p, err)
+		}
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		if err := Check(items, path+"[]"); err != nil {
+			return err
+		}
+	}
+	props, _ := schema["properties"].(map[string]any)
+	for name, prop := range props {
+		if m, ok := prop.(map[string]any); ok {
+			if err := Check(m, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matching 返回 v 满足的 schema 个数
+func (o Options) matching(schemas []map[string]any, v any) int {
+	n := 0
+	for _, s := range schemas {
+		var errs []string
+		o.validate(s, v, "", &errs)
+		if len(errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func hasAnyType(v any, types []string) bool {
+	for _, t := range types {
+		if hasType(v, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasType(v any, t string) bool {
+	switch t {
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "null":
+		return v == nil
+	}
+	// 未知类型不做限制
+	return true
+}
+
+// TypeOf 返回 json 解码出来的值的 schema 类型，整数值的 float64 为 integer
+func TypeOf(v any) string {
+	switch val := v.(type) {
+	case string:
+		return "string"
+	case float64:
+		if val == math.Trunc(val) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// inEnum 按 json 编码比较，避免 1 与 1.0 这类数值类型差异
+func inEnum(v any, enum []any) bool {
+	b, _ := json.Marshal(v)
+	for _, e := range enum {
+		if eb, _ := json.Marshal(e); string(eb) == string(b) {
+			return true
+		}
+		if f, ok := floatOf(e); ok {
+			if vf, ok := v.(float64); ok && vf == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// StringList 读取 type、required 这类字符串或字符串数组，配置中解析出来的是 []any，代码中生成的是 []string
+func StringList(v any) []string {
+	switch val := v.(type) {
+	case string:
+		return []string{val}
+	case []string:
+		return val
+	case []any:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func schemaList(v any) []map[string]any {
+	list, _ := v.([]any)
+	out := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(map[string]any); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func floatOf(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func intOf(v any) (int, bool) {
+	f, ok := floatOf(v)
+	return int(f), ok
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// matchesFormat 校验常见的 format，不认识的 format 不做限制
+func matchesFormat(format, s string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "date":
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	case "uuid":
+		return uuidPattern.MatchString(s)
+	case "ipv4":
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil
+	case "ipv6":
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() == nil
+	}
+	return true
+}
+
+var patterns sync.Map
+
+// compilePattern 缓存编译好的 pattern，无效的 pattern 不做限制
+func compilePattern(p string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(p); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(p, re)
+	return re, nil
+}
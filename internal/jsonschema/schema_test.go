@@ -0,0 +1,75 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":    map[string]any{"type": []any{"integer", "string"}},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string", "pattern": "^[a-z]+$"}, "maxItems": 2.0},
+			"count": map[string]any{"type": "integer", "minimum": 1.0, "exclusiveMaximum": 10.0},
+			"kind":  map[string]any{"const": "user"},
+			"target": map[string]any{"oneOf": []any{
+				map[string]any{"type": "object", "required": []any{"email"}},
+				map[string]any{"type": "object", "required": []any{"phone"}},
+			}},
+		},
+		"additionalProperties": false,
+	}
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{`{"id": 1, "tags": ["a"], "count": 9, "kind": "user", "target": {"email": "a@b"}}`, nil},
+		{`{"id": "x1"}`, nil},
+		{`{"id": 1.5}`, []string{"body.id: expected integer or string, got number"}},
+		{`{"tags": ["A", "b", "c"]}`, []string{"body.tags: must have at most 2 items", "body.tags[0]: must match pattern ^[a-z]+$"}},
+		{`{"count": 10, "kind": "group"}`, []string{"body.count: must be < 10", "body.kind: must be \"user\""}},
+		{`{"target": {"email": "a", "phone": "1"}}`, []string{"body.target: must match exactly one schema in oneOf"}},
+		{`{"extra": 1}`, []string{"body.extra: is not allowed"}},
+		{`{"id": null}`, []string{"body.id: expected integer or string, got null"}},
+	}
+	for _, tt := range tests {
+		var body any
+		json.Unmarshal([]byte(tt.body), &body)
+		if got := Validate(schema, body, "body", Options{}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %q, got %q", tt.body, tt.want, got)
+		}
+	}
+}
+
+func TestValidateNullAsAbsent(t *testing.T) {
+	// rest2mcp 把 null 当作未传入：不满足 required，也不按属性的 schema 校验
+	schema := map[string]any{
+		"type":     "object",
+		"required": []string{"sku"},
+		"properties": map[string]any{
+			"sku":   map[string]any{"type": "string", "format": "uuid"},
+			"count": map[string]any{"type": "integer", "minimum": 1},
+		},
+	}
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{`{"sku": "0b7e4c1a-2f3d-4e5f-8a9b-0c1d2e3f4a5b", "count": null}`, nil},
+		{`{"sku": null, "count": 0}`, []string{"items.sku: is required", "items.count: must be >= 1"}},
+		{`{"sku": "x"}`, []string{"items.sku: must be a valid uuid"}},
+	}
+	for _, tt := range tests {
+		var body any
+		json.Unmarshal([]byte(tt.body), &body)
+		if got := Validate(schema, body, "items", Options{NullAsAbsent: true}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %q, got %q", tt.body, tt.want, got)
+		}
+	}
+	if err := Check(map[string]any{"items": map[string]any{"pattern": "("}}, "tags"); err == nil || !strings.Contains(err.Error(), "tags[]: invalid pattern") {
+		t.Errorf("expected invalid pattern error, got %v", err)
+	}
+}